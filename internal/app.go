@@ -2,60 +2,213 @@ package internal
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
+	"articles-service/internal/articlesapi"
 	"articles-service/internal/articlesprocessor"
+	"articles-service/internal/diff"
+	"articles-service/internal/domains"
+	"articles-service/internal/filter"
+	"articles-service/internal/logging"
+	"articles-service/internal/metrics"
+	"articles-service/internal/output"
+	"articles-service/internal/report"
+	"articles-service/internal/runs"
+	"articles-service/internal/scheduler"
+	"articles-service/internal/server"
+	"articles-service/internal/snapshot"
 	"articles-service/internal/storage"
+	"articles-service/internal/tracing"
 )
 
-const maxLimit = 100
+var tracer = otel.Tracer("articles-service/internal")
+
+// ErrPartial is returned by Run with -partial when the crawl was interrupted
+// and the top collected so far was written.
+var ErrPartial = articlesprocessor.ErrPartial
+
+// errRunTimeout is the cause of the run canceled by -timeout.
+var errRunTimeout = errors.New("run timeout")
 
 type App struct {
-	logger     *zap.Logger
-	proc       *articlesprocessor.ArticlesProcessor
-	resultChan chan []string
+	logger *zap.Logger
+	args   args
+	proc   *articlesprocessor.ArticlesProcessor
+	filter *filter.Chain
+	writer output.Writer
+	// flushes and closes log files
+	closeLog func() error
+	// flushes spans left, noop without tracing
+	shutdownTracing func(ctx context.Context) error
+	// nil if metrics are not served
+	metrics *metrics.Metrics
+	// nil if runs are not persisted
+	store *snapshot.Store
+	// sequence number of the run id, runs never overlap
+	seq uint64
+	// last finished run of the one-shot mode
+	last *runs.Run
+	// current result of the continuous mode, replaced atomically by every successful run
+	current    atomic.Pointer[runs.Run]
+	resultChan chan *runs.Run
+	// the current or the last run for state dumps
+	live atomic.Pointer[liveRun]
+
+	// command line arguments to reload the config with, see reload.go
+	argv []string
+	// log level changed by reloads, nil if the logger is not built by the app
+	level *zap.AtomicLevel
+	// config reloaded by the signal, applied before the next run
+	mu       sync.Mutex
+	reloaded *reloaded
+}
+
+// NewApp builds the app of the top command from the command line arguments(without the program name),
+// flag.ErrHelp is returned when help is requested.
+func NewApp(argv []string) (*App, error) {
+	return newCommandApp(cmdTop, argv)
 }
 
-func NewApp() (*App, error) {
+// newCommandApp builds the app of the pipeline command, see cli.go.
+func newCommandApp(cmd string, argv []string) (*App, error) {
+	// pars run args
+	args, err := parseCommandArgs(cmd, argv)
+	if err != nil {
+		return nil, invalidArgs(err)
+	}
+	if args.printConfig {
+		if err = printConfig(os.Stdout, args.settings); err != nil {
+			return nil, err
+		}
+		return nil, ErrConfigPrinted
+	}
+
 	// logger
-	logger, err := zap.NewProduction()
+	level := zap.NewAtomicLevel()
+	args.log.AtomicLevel = &level
+	logger, closeLog, err := logging.New(args.log)
 	if err != nil {
-		log.Fatalf("cannot initialize zap logger: %v", err)
+		return nil, fmt.Errorf("cannot initialize zap logger: %w", err)
 	}
-	defer logger.Sync()
 
-	// pars run args
-	var limit int
-	flag.IntVar(&limit, "l", 0, "limit")
-	flag.Parse()
-	if limit == 0 {
-		log.Fatal("please provide limit of articles")
+	if len(args.ignoredEnv) > 0 {
+		logger.Warn("ignoring unknown settings in env", zap.Strings("vars", args.ignoredEnv))
 	}
-	if limit > maxLimit {
-		log.Fatalf("max limit is out of range: %v", maxLimit)
+
+	app, err := newApp(logger, args)
+	if err != nil {
+		_ = closeLog()
+		return nil, err
+	}
+	app.closeLog = closeLog
+	app.argv, app.level = argv, &level
+
+	return app, nil
+}
+
+// newApp builds the app from already parsed args.
+func newApp(logger *zap.Logger, args args) (*App, error) {
+	if err := args.validate(); err != nil {
+		return nil, invalidArgs(err)
+	}
+	chain, err := args.filter.Build()
+	if err != nil {
+		return nil, invalidArgs(fmt.Errorf("invalid filters: %w", err))
 	}
 
 	// storage
-	st := storage.New(logger, limit)
+	st := storage.New(logger, args.limit)
 	// processor
-	ap := articlesprocessor.New(logger, limit, st)
+	ap := articlesprocessor.New(logger, args.limit, st)
+	ap.SetFilter(chain)
+	ap.SetClientConfig(args.api)
+	ap.SetWorkers(args.workers)
+	ap.SetPartial(args.partial, args.drainTimeout)
+	if args.incremental != "" {
+		ap.SetIncremental(args.incremental)
+	}
+	if args.checkpoint != "" {
+		ap.SetCheckpoint(args.checkpoint, args.checkpointInterval)
+	}
+	if args.resume != "" {
+		cp, err := articlesprocessor.LoadCheckpoint(args.resume)
+		switch {
+		// nothing to resume: the previous crawl finished or never started
+		case errors.Is(err, os.ErrNotExist):
+			logger.Info("no checkpoint to resume, starting from the first page", zap.String("path", args.resume))
+		case err != nil:
+			return nil, fmt.Errorf("load checkpoint: %w", err)
+		default:
+			if err = ap.Resume(cp); err != nil {
+				return nil, fmt.Errorf("resume: %w", err)
+			}
+		}
+	}
 
-	return &App{
+	app := &App{
 		logger:     logger,
+		args:       args,
 		proc:       ap,
-		resultChan: make(chan []string, 1),
-	}, nil
+		filter:     chain,
+		resultChan: make(chan *runs.Run, 1),
+	}
+
+	if args.serve || args.metricsAddr != "" {
+		app.metrics = metrics.New()
+		ap.SetMetrics(app.metrics)
+	}
+
+	if app.shutdownTracing, err = tracing.Setup(context.Background(), args.trace); err != nil {
+		return nil, fmt.Errorf("setup tracing: %w", err)
+	}
+
+	if args.snapshotDir != "" {
+		if app.store, err = snapshot.Open(args.snapshotDir); err != nil {
+			return nil, fmt.Errorf("open snapshot store: %w", err)
+		}
+	}
+
+	if app.writer, err = app.newWriter(args); err != nil {
+		return nil, invalidArgs(err)
+	}
+
+	return app, nil
+}
+
+// newWriter builds the writer of the result by the output args.
+func (a *App) newWriter(args args) (output.Writer, error) {
+	switch {
+	case args.template != "":
+		return output.NewTemplate(args.template, a.report)
+	case args.format.IsFeed():
+		return output.NewFeed(args.format, args.feed), nil
+	default:
+		return output.NewWriter(args.format)
+	}
 }
 
 func (a *App) Close() {
+	if a.closeLog != nil {
+		if err := a.closeLog(); err != nil {
+			log.Printf("cannot close log files: %v", err)
+		}
+		return
+	}
 	if a.logger != nil {
 		_ = a.logger.Sync()
 	}
@@ -66,37 +219,84 @@ func (a *App) Run(ctx context.Context) error {
 
 	// context with os signals cancel chan
 	// any process/app/service must be able to shut down gracefully(avoid kill)
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1)
+	stopSignals := a.args.stopSignals
+	if len(stopSignals) == 0 {
+		stopSignals = defaultStopSignals
+	}
+	signals := newSignalDispatcher(stopSignals...)
+	signals.Handle(syscall.SIGUSR1, a.dumpState)
+	if a.args.serve || a.args.watch {
+		signals.Handle(syscall.SIGHUP, a.reload)
+	}
+	sigCtx, stop := signals.Start(ctx)
 	defer stop()
+	defer a.flushTraces()
+
+	if a.args.metricsAddr != "" {
+		stopMetrics := a.serveMetrics(sigCtx)
+		defer stopMetrics()
+	}
+
+	if a.args.serve || a.args.watch {
+		return a.continuous(sigCtx)
+	}
 
 	// "errgroup" instead of "WaitGroup" because:
 	// - allows return an error from goroutine
 	// - group errors from multiple gorutines into one
 	// - wg.Add(1), wg.Done() - automatically under the hood, so never catch deadlock if you forget something ;-)
 	// - allows orchestration of parallel processes through the context.Context(gracefull shut down)
-	//start := time.Now()
-	g, ctx := errgroup.WithContext(ctx)
+	runCtx, cancel := a.runContext(sigCtx)
+	defer cancel()
+	g, ctx := errgroup.WithContext(runCtx)
 	g.Go(func() error {
-		articles, err := a.proc.TopArticles(ctx)
+		run, err := a.crawl(ctx)
+		a.last = run
 		if err != nil {
 			return fmt.Errorf("ProcessArticles error: %w", err)
 		}
+		// stopped between pages without an error, the result could be incomplete
+		if ctx.Err() != nil {
+			return nil
+		}
 
-		a.resultChan <- articles
+		a.resultChan <- run
 
 		return nil
 	})
 
 	// waiting when processing finished or sigurg signal
+	var (
+		writeErr error
+		written  bool
+	)
 	select {
 	case <-ctx.Done():
-	case topArticles := <-a.resultChan:
-		for _, val := range topArticles {
-			fmt.Println(val)
-		}
+	case run := <-a.resultChan:
+		writeErr, written = a.write(run), true
 	}
 
-	if err := g.Wait(); err != nil {
+	err := g.Wait()
+	partial := errors.Is(err, ErrPartial)
+	// interrupted run is incomplete, nothing to keep
+	if sigCtx.Err() == nil && !partial {
+		a.persist(a.last)
+	}
+	if partial {
+		writeErr = a.writePartial(a.last)
+	}
+	a.writeSummary(a.last)
+	if err == nil || partial && writeErr != nil {
+		err = writeErr
+	}
+	if !partial {
+		err = interrupted(runCtx, err, written)
+	}
+	if errors.Is(err, ErrPartial) {
+		a.logger.Warn("articles service returning a partial result", zap.Error(err))
+		return err
+	}
+	if err != nil {
 		a.logger.Error("articles service returning an error", zap.Error(err))
 		return err
 	}
@@ -106,4 +306,322 @@ func (a *App) Run(ctx context.Context) error {
 	return nil
 }
 
+// continuous reruns the pipeline on schedule(watch and server modes)
+// until the signal context is canceled. The HTTP client and the page cache are reused between runs.
+func (a *App) continuous(ctx context.Context) error {
+	a.proc.SetCache(articlesapi.NewMemoryCache(), a.args.cacheTTL)
+	sched := scheduler.New(a.logger, a.args.interval)
+
+	var (
+		history *runs.History
+		srv     *server.Server
+	)
+	if a.args.serve {
+		history = runs.NewHistory(a.args.history)
+		srv = server.New(a.logger, a.args.addr, history, a.args.feed)
+		srv.SetSkippedTicks(sched.Skipped)
+		srv.SetMetrics(a.metrics.Handler())
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	if srv != nil {
+		g.Go(func() error {
+			return srv.Run(ctx)
+		})
+	}
+	g.Go(func() error {
+		sched.Run(ctx, func(ctx context.Context) {
+			a.applyReloaded()
+			runCtx, cancel := a.runContext(ctx)
+			run, err := a.crawl(runCtx)
+			cancel()
+			// interrupted run is incomplete, nothing to keep
+			if ctx.Err() != nil {
+				return
+			}
+			a.persist(run)
+			if history != nil {
+				history.Add(run)
+			}
+			if err != nil {
+				a.logger.Error("scheduled run failed", zap.String("run_id", run.ID), zap.Error(err))
+				return
+			}
+
+			prev := a.current.Swap(run)
+			a.logChanges(prev, run, sched.Skipped())
+			if srv != nil {
+				srv.Publish(run)
+			}
+			if a.args.watch {
+				if err = a.write(run); err != nil {
+					a.logger.Error("cannot write the result", zap.Error(err))
+				}
+			}
+			a.writeSummary(run)
+		})
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		a.logger.Error("articles service returning an error", zap.Error(err))
+		return err
+	}
+
+	a.logger.Info("articles service exited properly", zap.Uint64("runs", sched.Runs()), zap.Uint64("skipped_ticks", sched.Skipped()))
+
+	return nil
+}
+
+// logChanges logs the compact summary of changes against the previous run.
+func (a *App) logChanges(prev, run *runs.Run, skipped uint64) {
+	fields := []zap.Field{
+		zap.String("run_id", run.ID),
+		zap.Int("articles", len(run.Top)),
+		zap.Int64("wall_time_ms", run.Report.WallTimeMs),
+		zap.Int("cache_hits", run.Report.Pages.CacheHits),
+		zap.Uint64("skipped_ticks", skipped),
+	}
+	if prev != nil {
+		d := diff.Compare(prev.Top, run.Top)
+		fields = append(fields,
+			zap.Int("entered", d.Count(diff.Entered)),
+			zap.Int("left", d.Count(diff.Left)),
+			zap.Int("moved", d.Count(diff.Moved)),
+			zap.Int("comments_changed", d.Count(diff.Comments)),
+		)
+	}
+
+	a.logger.Info("scheduled run finished", fields...)
+}
+
+// interrupted marks the failed or not written run of the canceled context as interrupted,
+// the pipeline returns no error when it is stopped while no page is in flight.
+// The error is dropped when it only repeats the cause(e.g. -timeout).
+func interrupted(runCtx context.Context, err error, written bool) error {
+	cause := context.Cause(runCtx)
+	switch {
+	case runCtx.Err() == nil || err == nil && written:
+		return err
+	case err == nil || errors.Is(err, cause):
+		return fmt.Errorf("%w by %w", ErrInterrupted, cause)
+	default:
+		return fmt.Errorf("%w by %v: %w", ErrInterrupted, cause, err)
+	}
+}
+
+// crawl runs one pass of the pipeline with a fresh per run state,
+// the run is returned on failures too to keep its report.
+func (a *App) crawl(ctx context.Context) (*runs.Run, error) {
+	rec := report.NewRecorder()
+	a.proc.SetRecorder(rec)
+	a.filter.Reset()
+
+	authors := storage.NewAggregate(a.logger, func(ar storage.Article) string { return ar.Author })
+	collectors := []articlesprocessor.Collector{authors}
+
+	var byDomain *storage.Aggregate
+	if a.args.byDomain || a.args.command == cmdExport {
+		byDomain = storage.NewAggregate(a.logger, func(ar storage.Article) string {
+			return domains.Domain(ar.URL, a.args.registrable)
+		})
+		collectors = append(collectors, byDomain)
+	}
+	var windows *storage.Windowed
+	if a.args.windows != nil {
+		windows = storage.NewWindowed(a.logger, a.args.limit, *a.args.windows)
+		collectors = append(collectors, windows)
+	}
+	// comments of the story are spread over its articles
+	var stories *storage.Aggregate
+	if a.args.command == cmdStories || a.args.command == cmdExport {
+		stories = storage.NewAggregate(a.logger, func(ar storage.Article) string { return ar.Name })
+		collectors = append(collectors, stories)
+	}
+	a.proc.SetCollectors(collectors...)
+
+	a.seq++
+	run := &runs.Run{StartedAt: time.Now().UTC()}
+	run.ID = runs.NewID(run.StartedAt, a.seq)
+	a.live.Store(&liveRun{id: run.ID, rec: rec})
+
+	ctx, span := tracer.Start(ctx, "run", trace.WithAttributes(
+		attribute.String("run.id", run.ID),
+		attribute.Int("limit", a.args.limit),
+	))
+	defer span.End()
+	articles, err := a.proc.TopArticles(ctx)
+	if err != nil && !errors.Is(err, ErrPartial) && errors.Is(context.Cause(ctx), errRunTimeout) {
+		err = fmt.Errorf("%w after %s: %w", errRunTimeout, a.args.timeout, err)
+	}
+	var partial *articlesprocessor.PartialError
+	if errors.As(err, &partial) {
+		run.Partial = &runs.Coverage{PagesDone: partial.PagesDone, TotalPages: partial.TotalPages}
+		span.SetAttributes(attribute.Bool("partial", true))
+	}
+	rec.Finish()
+	run.FinishedAt = time.Now().UTC()
+	// interrupted run is neither success nor failure, timed out one is failed
+	if ctx.Err() == nil || errors.Is(context.Cause(ctx), errRunTimeout) {
+		a.metrics.RunFinished(run.FinishedAt.Sub(run.StartedAt), err != nil)
+	}
+	run.Report = rec.Report(a.filter.Rejected())
+	if err != nil && partial == nil {
+		run.Error = err.Error()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "run failed")
+		return run, err
+	}
+	span.SetAttributes(attribute.Int("articles", len(articles)))
+
+	run.Top = output.Articles(articles)
+	run.Authors = output.Authors(authors.Top(a.args.limit, storage.RankByComments))
+	if byDomain != nil {
+		run.Domains = output.Groups(byDomain.Top(a.args.limit, a.args.domainRank))
+	}
+	if windows != nil {
+		run.Windows = output.Windows(windows.Top())
+	}
+	if stories != nil {
+		run.Stories = output.Stories(stories.Top(a.args.limit, storage.RankByComments))
+	}
+
+	// the partial error is kept to report it
+	return run, err
+}
+
+// runContext bounds the run by -timeout.
+func (a *App) runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.args.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, a.args.timeout, errRunTimeout)
+}
+
+// write writes the result of the run to stdout or atomically replaces the output file.
+func (a *App) write(run *runs.Run) error {
+	err := output.WriteFile(a.args.output, func(w io.Writer) error {
+		switch a.args.command {
+		case cmdStats:
+			return writeIndentedJSON(w, run.Report)
+		case cmdExport:
+			return writeIndentedJSON(w, run)
+		}
+		return a.writer.Write(w, a.render(run))
+	})
+	if err != nil {
+		return fmt.Errorf("write result: %w", err)
+	}
+	return nil
+}
+
+// writePartial writes the top of the interrupted run, the result is marked as partial on stderr
+// to keep the output format intact.
+func (a *App) writePartial(run *runs.Run) error {
+	cov := run.Partial
+	if _, err := fmt.Fprintf(os.Stderr, "partial result: %d of %d pages\n", cov.PagesDone, cov.TotalPages); err != nil {
+		return err
+	}
+	return a.write(run)
+}
+
+// flushTraces exports spans left before exit.
+func (a *App) flushTraces() {
+	if a.shutdownTracing == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.logger.Error("cannot flush traces", zap.Error(err))
+	}
+}
+
+// serveMetrics serves /metrics on -metrics-addr until the returned stop is called,
+// the metrics server failure doesn't stop the crawl.
+func (a *App) serveMetrics(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := metrics.Serve(ctx, a.logger, a.args.metricsAddr, a.metrics); err != nil {
+			a.logger.Error("metrics server stopped", zap.Error(err))
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// persist stores the run into the snapshot store if enabled,
+// failed runs are stored too to keep their reports.
+func (a *App) persist(run *runs.Run) {
+	if a.store == nil || run == nil {
+		return
+	}
+
+	snap := &snapshot.Snapshot{Run: *run, Params: a.args.params}
+	if err := a.store.Put(snap); err != nil {
+		a.logger.Error("cannot persist the run", zap.String("run_id", run.ID), zap.Error(err))
+		return
+	}
+	// the store makes the id unique among the stored runs
+	run.ID = snap.ID
+}
+
+// writeSummary logs the run report and writes it if requested,
+// the report is written on failures too to see what went wrong upstream.
+func (a *App) writeSummary(run *runs.Run) {
+	if run == nil {
+		return
+	}
+	rep := run.Report
+
+	a.logger.Info("run summary",
+		zap.Int64("wall_time_ms", rep.WallTimeMs),
+		zap.Int("pages_fetched", rep.Pages.Fetched),
+		zap.Int("pages_failed", rep.Pages.Failed),
+		zap.Int("pages_retried", rep.Pages.Retried),
+		zap.Int("rows_seen", rep.Rows.Seen),
+		zap.Int("rows_accepted", rep.Rows.Accepted),
+		zap.Any("rows_dropped", rep.Rows.Dropped),
+	)
+
+	if a.args.summary == "" {
+		return
+	}
+	if err := rep.Write(a.args.summary); err != nil {
+		a.logger.Error("cannot write run summary", zap.Error(err))
+	}
+}
+
+// report returns the report of the last finished run for templates.
+func (a *App) report() report.Report {
+	if run := a.current.Load(); run != nil {
+		return run.Report
+	}
+	if a.last == nil {
+		return report.Report{}
+	}
+	return a.last.Report
+}
+
+// render returns the output records of the run.
+func (a *App) render(run *runs.Run) []output.Record {
+	switch {
+	case a.args.command == cmdAuthors:
+		return run.Authors
+	case a.args.command == cmdStories:
+		return run.Stories
+	case a.args.windows != nil:
+		return run.Windows
+	case a.args.byDomain:
+		return run.Domains
+	default:
+		return run.Top
+	}
+}
+
 func (a *App) Logger() *zap.Logger { return a.logger }
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"articles-service/internal/articlesapi"
+)
+
+func TestApp_Run_Timeout(t *testing.T) {
+	// the first page is served, the rest hang until the request is canceled
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			<-r.Context().Done()
+			return
+		}
+		title, comments := "a", 5
+		_ = json.NewEncoder(w).Encode(articlesapi.Response{
+			Page: 1, PerPage: 1, Total: 3, TotalPages: 3,
+			Data: articlesapi.Articles{{Title: &title, NumComments: &comments}},
+		})
+	}))
+	defer srv.Close()
+
+	run := func(t *testing.T, flags ...string) (string, error) {
+		out := filepath.Join(t.TempDir(), "top.txt")
+		argv := append([]string{"-l=3", "-api-url=" + srv.URL, "-attempts=1", "-timeout=200ms", "-output=" + out}, flags...)
+		args, err := parseArgs(argv)
+		require.NoError(t, err)
+		app, err := newApp(zap.NewNop(), args)
+		require.NoError(t, err)
+
+		err = app.Run(context.Background())
+		data, _ := os.ReadFile(out)
+		return string(data), err
+	}
+
+	t.Run("fails", func(t *testing.T) {
+		out, err := run(t)
+		require.ErrorIs(t, err, errRunTimeout)
+		assert.NotErrorIs(t, err, ErrPartial)
+		assert.Equal(t, ExitInterrupted, NewFailure(err).ExitCode)
+		assert.Empty(t, out)
+	})
+	t.Run("partial", func(t *testing.T) {
+		out, err := run(t, "-partial", "-drain-timeout=50ms")
+		require.ErrorIs(t, err, ErrPartial)
+		require.ErrorIs(t, err, errRunTimeout)
+		assert.Equal(t, ExitPartial, NewFailure(err).ExitCode)
+		assert.Equal(t, "a\n", out)
+	})
+}
+
+func TestInterrupted(t *testing.T) {
+	canceled, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("signal interrupt"))
+	errWrite := errors.New("write failed")
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		err             error
+		written         bool
+		wantInterrupted bool
+		wantCode        int
+	}{
+		{name: "finished", ctx: context.Background(), written: true, wantCode: ExitOK},
+		{name: "failed", ctx: context.Background(), err: errWrite, wantCode: ExitInternal},
+		{name: "written before the interrupt", ctx: canceled, written: true, wantCode: ExitOK},
+		{name: "stopped between pages without an error", ctx: canceled, wantInterrupted: true, wantCode: ExitInterrupted},
+		{name: "failed by the interrupt", ctx: canceled, err: context.Canceled, wantInterrupted: true, wantCode: ExitInterrupted},
+		{name: "write failed after the interrupt", ctx: canceled, err: errWrite, written: true, wantInterrupted: true, wantCode: ExitInterrupted},
+	}
+
+	t.Run("cause in the chain is not repeated", func(t *testing.T) {
+		timedOut, cancel := context.WithCancelCause(context.Background())
+		cancel(errRunTimeout)
+		err := interrupted(timedOut, fmt.Errorf("ProcessArticles error: %w after 1s: get page: %w", errRunTimeout, context.Canceled), false)
+		assert.EqualError(t, err, "interrupted by run timeout")
+		assert.ErrorIs(t, err, errRunTimeout)
+		assert.Equal(t, ExitInterrupted, NewFailure(err).ExitCode)
+	})
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := interrupted(tt.ctx, tt.err, tt.written)
+			assert.Equal(t, tt.wantInterrupted, errors.Is(err, ErrInterrupted), err)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+			if err != nil {
+				assert.Equal(t, tt.wantCode, NewFailure(err).ExitCode)
+			} else {
+				assert.Equal(t, ExitOK, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package internal
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/feed"
+	"articles-service/internal/filter"
+	"articles-service/internal/logging"
+	"articles-service/internal/output"
+	"articles-service/internal/storage"
+	"articles-service/internal/tracing"
+	"articles-service/internal/window"
+)
+
+// MaxLimit is the default of -max-limit, the upper bound of the limit.
+const MaxLimit = 100
+
+// args of the run
+type args struct {
+	// pipeline command, see cli.go
+	command string
+	limit   int
+	// upper bound of limit
+	maxLimit    int
+	byDomain    bool
+	domainRank  storage.RankBy
+	registrable bool
+	filter      filter.Config
+	summary     string
+	format      output.Format
+	output      string
+	template    string
+	feed        feed.Meta
+	// nil if top per time window is not requested
+	windows *window.Spec
+	// checkpoints of the one-shot crawl
+	checkpoint         string
+	checkpointInterval time.Duration
+	resume             string
+	// state file of the incremental crawl
+	incremental string
+	// write the top collected before the interrupt, in-flight pages are drained for up to drainTimeout
+	partial      bool
+	drainTimeout time.Duration
+	// persist every run into the store when set
+	snapshotDir string
+	// settings set by flags, the config file or env, stored with snapshots
+	params map[string]string
+	// dump the effective settings instead of running
+	printConfig bool
+	// effective settings by the config key
+	settings map[string]any
+	// ARTICLES_* env vars which are not settings
+	ignoredEnv []string
+	// upstream client and the page fetchers
+	api     articlesapi.Config
+	workers int
+	// deadline of the one-shot run or every scheduled run, 0 - none
+	timeout time.Duration
+	// logging and tracing of the process
+	log   logging.Config
+	trace tracing.Config
+	// serve /metrics on the separate address when set
+	metricsAddr string
+	// text|json error written to stderr on failure
+	errorFormat string
+	// signals stopping the app gracefully, the default ones when empty
+	stopSignals []os.Signal
+	// file of SIGUSR1 state dumps, stderr when empty
+	dumpFile string
+	// continuous modes
+	watch    bool
+	cacheTTL time.Duration
+	serve    bool
+	addr     string
+	interval time.Duration
+	history  int
+}
+
+// rawFlags are flag values parsed into args after the config is applied.
+type rawFlags struct {
+	configPath                  string
+	domainRank                  string
+	authors, excludeAuthors     string
+	allowDomains, denyDomains   string
+	createdAfter, createdBefore string
+	last, refTime, since, until string
+	windowKind, windowSize      string
+	windowStep                  string
+	format                      string
+	traceExporter               string
+	logFormat, logOutput        string
+	stopSignals                 string
+}
+
+// parseArgs parses the command line arguments of the top command(without the program name),
+// flag.ErrHelp is returned when help is requested.
+func parseArgs(argv []string) (args, error) {
+	return parseCommandArgs(cmdTop, argv)
+}
+
+// parseCommandArgs parses the arguments of the pipeline command, see cli.go.
+func parseCommandArgs(cmd string, argv []string) (args, error) {
+	var (
+		a   args
+		raw rawFlags
+	)
+	fs := newFlagSet(cmd, &a, &raw)
+	if err := fs.Parse(argv); err != nil {
+		return args{}, err
+	}
+	if raw.configPath == "" {
+		raw.configPath = os.Getenv(envConfig)
+	}
+	ignored, err := applySettings(fs, raw.configPath, os.Environ())
+	if err != nil {
+		return args{}, err
+	}
+	a.ignoredEnv = ignored
+	a.settings = effectiveConfig(fs)
+	a.command = cmd
+	switch cmd {
+	case cmdServe:
+		a.serve = true
+	case cmdDomains:
+		a.byDomain = true
+	}
+
+	a.params = make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		a.params[f.Name] = f.Value.String()
+	})
+
+	// the server slices the top by ?limit=
+	if a.serve && a.limit == 0 {
+		a.limit = a.maxLimit
+	}
+	// keep checkpointing the resumed crawl
+	if a.resume != "" && a.checkpoint == "" {
+		a.checkpoint = a.resume
+	}
+	logFmt, err := logging.ParseFormat(raw.logFormat)
+	if err != nil {
+		return args{}, err
+	}
+	a.log.Format = logFmt
+	a.log.Outputs = filter.SplitList(raw.logOutput)
+	if a.trace.Exporter, err = tracing.ParseExporter(raw.traceExporter); err != nil {
+		return args{}, err
+	}
+	if a.format, err = output.ParseFormat(raw.format); err != nil {
+		return args{}, err
+	}
+	if a.stopSignals, err = parseSignals(raw.stopSignals); err != nil {
+		return args{}, fmt.Errorf("stop-signals: %w", err)
+	}
+	rankBy, ok := storage.ParseRankBy(raw.domainRank)
+	if !ok {
+		return args{}, fmt.Errorf("unknown domain rank: %q", raw.domainRank)
+	}
+	a.domainRank = rankBy
+
+	a.filter.Authors = filter.SplitList(raw.authors)
+	a.filter.ExcludeAuthors = filter.SplitList(raw.excludeAuthors)
+	a.filter.AllowDomains = filter.SplitList(raw.allowDomains)
+	a.filter.DenyDomains = filter.SplitList(raw.denyDomains)
+	if raw.createdAfter != "" {
+		if a.filter.CreatedAfter, err = filter.ParseTime(raw.createdAfter); err != nil {
+			return args{}, fmt.Errorf("created-after: %w", err)
+		}
+	}
+	if raw.createdBefore != "" {
+		if a.filter.CreatedBefore, err = filter.ParseTime(raw.createdBefore); err != nil {
+			return args{}, fmt.Errorf("created-before: %w", err)
+		}
+	}
+
+	if a.filter.Window, err = parseRange(raw.last, raw.refTime, raw.since, raw.until); err != nil {
+		return args{}, err
+	}
+
+	if raw.windowKind != "" {
+		size, err := window.ParseDuration(raw.windowSize)
+		if err != nil {
+			return args{}, fmt.Errorf("window-size: %w", err)
+		}
+		var step time.Duration
+		if raw.windowStep != "" {
+			if step, err = window.ParseDuration(raw.windowStep); err != nil {
+				return args{}, fmt.Errorf("window-step: %w", err)
+			}
+		}
+		spec, err := window.NewSpec(window.Kind(raw.windowKind), size, step)
+		if err != nil {
+			return args{}, fmt.Errorf("invalid windows: %w", err)
+		}
+		a.windows = &spec
+	}
+
+	return a, a.validate()
+}
+
+// newFlagSet defines flags of the pipeline command: the crawl, api and log flags are common,
+// the rest are registered by the commands using them. Settings are shared by name,
+// so one config file serves all commands, see applySettings.
+func newFlagSet(cmd string, a *args, raw *rawFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("top-articles "+cmd, flag.ContinueOnError)
+	fs.Usage = func() { commandUsage(fs, cmd) }
+	// defaults of the flags the command doesn't register
+	raw.format, raw.domainRank = string(output.Text), string(storage.RankByComments)
+
+	commonFlags(fs, a, raw)
+	if cmd != cmdServe {
+		oneShotFlags(fs, a)
+	}
+	if cmd == cmdTop || cmd == cmdServe {
+		serverFlags(fs, a)
+	}
+	if cmd == cmdTop {
+		// the serve and domains commands of the flags only command line
+		fs.BoolVar(&a.serve, "serve", false, "run crawls on schedule and serve the results over HTTP")
+		fs.BoolVar(&a.byDomain, "domains", false, "print the domain leaderboard instead of the top articles")
+	}
+	switch cmd {
+	case cmdTop, cmdAuthors, cmdStories, cmdDomains:
+		formatFlags(fs, a, raw)
+		feedFlags(fs, a)
+	case cmdServe:
+		feedFlags(fs, a)
+	case cmdStats, cmdExport:
+		fs.StringVar(&a.output, "output", "", "write the result to the file(atomically) instead of stdout")
+	}
+	if cmd == cmdTop || cmd == cmdDomains || cmd == cmdExport {
+		fs.StringVar(&raw.domainRank, "domain-rank", string(storage.RankByComments), "rank domains by: comments|articles")
+		fs.BoolVar(&a.registrable, "registrable", false, "collapse hosts to the registrable domain(news.bbc.co.uk -> bbc.co.uk)")
+	}
+	if cmd == cmdTop || cmd == cmdExport {
+		fs.StringVar(&raw.windowKind, "windows", "", "top per time window: tumbling|sliding")
+		fs.StringVar(&raw.windowSize, "window-size", "1d", "size of the time window")
+		fs.StringVar(&raw.windowStep, "window-step", "", "step of the sliding window")
+	}
+
+	return fs
+}
+
+// commonFlags are flags of every pipeline command.
+func commonFlags(fs *flag.FlagSet, a *args, raw *rawFlags) {
+	fs.IntVar(&a.limit, "l", 0, "limit")
+	fs.IntVar(&a.maxLimit, "max-limit", MaxLimit, "upper bound of -l")
+	fs.StringVar(&raw.configPath, "config", "", "YAML/JSON config file, default from "+envConfig)
+	fs.BoolVar(&a.printConfig, "print-config", false, "print the effective config merged from defaults, the config file, env and flags, then exit")
+	def := articlesapi.DefaultConfig()
+	fs.StringVar(&a.api.BaseURL, "api-url", def.BaseURL, "articles api endpoint")
+	fs.Float64Var(&a.api.RPS, "rps", def.RPS, "max requests per second to the articles api")
+	fs.IntVar(&a.api.Burst, "burst", def.Burst, "burst of requests over -rps")
+	fs.IntVar(&a.api.MaxAttempts, "attempts", def.MaxAttempts, "attempts of the page fetch on transient errors")
+	fs.DurationVar(&a.api.RetryDelay, "retry-delay", def.RetryDelay, "delay before the first retry, doubles with every retry")
+	fs.DurationVar(&a.api.Timeout, "api-timeout", def.Timeout, "timeout of the page request including the body read")
+	fs.DurationVar(&a.api.HeaderTimeout, "api-header-timeout", def.HeaderTimeout, "wait for the response headers of the page request")
+	fs.IntVar(&a.api.MaxIdleConns, "api-idle-conns", def.MaxIdleConns, "idle keep-alive connections to the articles api")
+	fs.DurationVar(&a.api.IdleConnTimeout, "api-idle-timeout", def.IdleConnTimeout, "how long idle connections are kept")
+	fs.IntVar(&a.api.MaxConnsPerHost, "api-max-conns", def.MaxConnsPerHost, "max connections to the articles api(0 - no limit)")
+	fs.DurationVar(&a.timeout, "timeout", 0, "deadline of the whole run(of every scheduled run in watch/serve modes), 0 - no deadline")
+	fs.IntVar(&a.workers, "workers", 0, "page fetchers(0 - 2 per CPU)")
+	fs.DurationVar(&a.interval, "interval", 10*time.Minute, "interval between scheduled crawls(watch/serve modes)")
+	fs.DurationVar(&a.cacheTTL, "cache-ttl", 0, "serve cached pages younger than ttl without requests in watch/serve modes(0 - always revalidate)")
+	fs.StringVar(&a.incremental, "incremental", "", "refetch only pages changed since the previous run, the state is kept in the file")
+	fs.StringVar(&a.snapshotDir, "snapshot-dir", "", "persist every run(result, report and flags) into the snapshot store directory")
+	fs.StringVar(&a.summary, "summary", "", "write the JSON run report to the file or stderr(-)")
+	fs.StringVar(&a.log.Level, "log-level", "info", "log level: debug|info|warn|error")
+	fs.StringVar(&raw.logFormat, "log-format", string(logging.JSON), "log encoding: json|console(colored on terminals)")
+	fs.BoolVar(&a.log.Development, "log-dev", false, "development logging: stacktraces from warn, caller and readable time")
+	fs.BoolVar(&a.log.Sampling, "log-sampling", true, "log the first 100 entries with the same message per second and every 100th after")
+	fs.StringVar(&raw.logOutput, "log-output", logging.Stderr, "comma separated log outputs: stdout, stderr or files(rotated)")
+	fs.IntVar(&a.log.MaxSize, "log-max-size", 100, "megabytes of the log file before rotation")
+	fs.IntVar(&a.log.MaxBackups, "log-max-backups", 5, "rotated log files kept(0 - all)")
+	fs.IntVar(&a.log.MaxAge, "log-max-age", 0, "days rotated log files are kept(0 - forever)")
+	fs.BoolVar(&a.log.Compress, "log-compress", false, "gzip rotated log files")
+	fs.StringVar(&raw.traceExporter, "trace-exporter", "", "export tracing spans: otlp|stdout|file")
+	fs.StringVar(&a.trace.Endpoint, "trace-endpoint", "", "OTLP/HTTP endpoint(host:port or url), default from OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318")
+	fs.StringVar(&a.trace.File, "trace-file", "traces.jsonl", "file of the file trace exporter, a span per line")
+	fs.StringVar(&a.errorFormat, "error-format", ErrorText, "error written to stderr on failure: text|json(code, message, failed pages, causes)")
+	fs.StringVar(&raw.stopSignals, "stop-signals", "INT,TERM", "comma separated signals stopping the app gracefully")
+	fs.StringVar(&a.dumpFile, "dump-file", "", "write the live state dump on SIGUSR1 to the file(atomically) instead of stderr")
+	fs.StringVar(&a.metricsAddr, "metrics-addr", "", "serve prometheus /metrics on the address(any mode), the server mode serves it on -addr too")
+	// filters
+	fs.StringVar(&raw.authors, "author", "", "comma separated authors to include")
+	fs.StringVar(&raw.excludeAuthors, "exclude-author", "", "comma separated authors to exclude")
+	fs.StringVar(&a.filter.TitleRegex, "title-regex", "", "include only titles matching the regex")
+	fs.Uint64Var(&a.filter.MinComments, "min-comments", 0, "min number of comments")
+	fs.Uint64Var(&a.filter.MaxComments, "max-comments", 0, "max number of comments(0 - unlimited)")
+	fs.StringVar(&raw.createdAfter, "created-after", "", "include articles created at or after(RFC3339 or YYYY-MM-DD)")
+	fs.StringVar(&raw.createdBefore, "created-before", "", "include articles created before(RFC3339 or YYYY-MM-DD)")
+	fs.StringVar(&raw.allowDomains, "allow-domain", "", "comma separated domains to include(with subdomains)")
+	fs.StringVar(&raw.denyDomains, "deny-domain", "", "comma separated domains to exclude(with subdomains)")
+	fs.BoolVar(&a.filter.RequireURL, "require-url", false, "include only articles with url")
+	// time range
+	fs.StringVar(&raw.last, "last", "", "top of articles created in the last duration relative to -ref-time(36h, 7d, 1w)")
+	fs.StringVar(&raw.refTime, "ref-time", "", "reference time for -last(RFC3339 or YYYY-MM-DD), default now")
+	fs.StringVar(&raw.since, "since", "", "top of articles created at or after(RFC3339 or YYYY-MM-DD)")
+	fs.StringVar(&raw.until, "until", "", "top of articles created before(RFC3339 or YYYY-MM-DD)")
+}
+
+// oneShotFlags are flags of the commands writing the result: the one-shot crawl state and the watch mode.
+func oneShotFlags(fs *flag.FlagSet, a *args) {
+	fs.BoolVar(&a.watch, "watch", false, "rerun the crawl every -interval writing the result each time")
+	fs.StringVar(&a.checkpoint, "checkpoint", "", "save the crawl progress to the file to -resume the interrupted crawl")
+	fs.DurationVar(&a.checkpointInterval, "checkpoint-interval", 10*time.Second, "min interval between checkpoint writes")
+	fs.StringVar(&a.resume, "resume", "", "continue the interrupted crawl from the checkpoint file")
+	fs.BoolVar(&a.partial, "partial", false, "on interrupt write the top collected so far marked as partial(exit code 3)")
+	fs.DurationVar(&a.drainTimeout, "drain-timeout", 10*time.Second, "max time to finish in-flight pages of the interrupted -partial crawl, 0 - no limit")
+}
+
+func serverFlags(fs *flag.FlagSet, a *args) {
+	fs.StringVar(&a.addr, "addr", ":8080", "address of the HTTP server")
+	fs.IntVar(&a.history, "history", 50, "number of runs kept in memory in the server mode")
+}
+
+// formatFlags are flags of the commands writing records.
+func formatFlags(fs *flag.FlagSet, a *args, raw *rawFlags) {
+	fs.StringVar(&raw.format, "format", string(output.Text), "output format: "+strings.Join(output.Formats(), "|"))
+	fs.StringVar(&a.template, "template", "", "render the result with text/template, inline or @file")
+	fs.StringVar(&a.output, "output", "", "write the result to the file(atomically) instead of stdout")
+}
+
+func feedFlags(fs *flag.FlagSet, a *args) {
+	fs.StringVar(&a.feed.Title, "feed-title", "", "title of the rss/atom feed")
+	fs.StringVar(&a.feed.Link, "feed-link", "", "link of the rss/atom feed")
+}
+
+// validate checks the args are consistent, args built in code are validated too.
+func (a *args) validate() error {
+	switch {
+	case a.limit == 0:
+		return errors.New("please provide limit of articles")
+	case a.maxLimit < 0:
+		return errors.New("max-limit must not be negative")
+	case a.limit < 0 || a.limit > cmp.Or(a.maxLimit, MaxLimit):
+		return fmt.Errorf("max limit is out of range: %v", cmp.Or(a.maxLimit, MaxLimit))
+	case a.api.RPS <= 0 || a.api.Burst <= 0:
+		// burst 0 fails every request, rps 0 stalls the crawl
+		return errors.New("rps and burst must be positive")
+	case a.api.MaxAttempts < 0:
+		return errors.New("attempts must not be negative")
+	case a.api.RetryDelay < 0 || a.api.Timeout < 0 || a.api.HeaderTimeout < 0 || a.api.IdleConnTimeout < 0:
+		return errors.New("retry-delay and api timeouts must not be negative")
+	case a.api.MaxIdleConns < 0 || a.api.MaxConnsPerHost < 0:
+		return errors.New("api-idle-conns and api-max-conns must not be negative")
+	case a.timeout < 0:
+		return errors.New("timeout must not be negative")
+	case a.workers < 0:
+		return errors.New("workers must not be negative")
+	case (a.serve || a.watch) && a.interval <= 0:
+		return errors.New("interval must be positive")
+	case (a.checkpoint != "" || a.resume != "") && (a.serve || a.watch):
+		return errors.New("-checkpoint/-resume are supported in the one-shot mode only")
+	case a.partial && (a.serve || a.watch):
+		return errors.New("-partial is supported in the one-shot mode only")
+	case a.drainTimeout < 0:
+		return errors.New("drain-timeout must not be negative")
+	case a.incremental != "" && (a.checkpoint != "" || a.resume != ""):
+		return errors.New("-incremental can't be combined with -checkpoint/-resume")
+	case a.template != "" && a.format != output.Text:
+		return errors.New("-template can't be combined with -format")
+	case a.windows != nil && a.byDomain:
+		return errors.New("-windows can't be combined with -domains")
+	// empty is text for args built in code
+	case a.errorFormat != "" && a.errorFormat != ErrorText && a.errorFormat != ErrorJSON:
+		return fmt.Errorf("unknown error format: %q", a.errorFormat)
+	case slices.Contains(a.stopSignals, os.Signal(syscall.SIGUSR1)):
+		return errors.New("-stop-signals: USR1 dumps the state")
+	case slices.Contains(a.stopSignals, os.Signal(syscall.SIGHUP)) && (a.serve || a.watch):
+		return errors.New("-stop-signals: HUP reloads the config in watch/serve modes")
+	}
+	if a.log.Level != "" {
+		if _, err := zapcore.ParseLevel(a.log.Level); err != nil {
+			return fmt.Errorf("log-level: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseRange returns the time range from either the relative(-last)
+// or the explicit(-since/-until) bounds.
+func parseRange(last, refTime, since, until string) (window.Range, error) {
+	var r window.Range
+
+	if last != "" {
+		if since != "" || until != "" {
+			return r, errors.New("-last can't be combined with -since/-until")
+		}
+		d, err := window.ParseDuration(last)
+		if err != nil {
+			return r, fmt.Errorf("last: %w", err)
+		}
+		ref := time.Now().UTC()
+		if refTime != "" {
+			if ref, err = filter.ParseTime(refTime); err != nil {
+				return r, fmt.Errorf("ref-time: %w", err)
+			}
+		}
+		return window.Last(ref, d), nil
+	}
+
+	var err error
+	if since != "" {
+		if r.Since, err = filter.ParseTime(since); err != nil {
+			return r, fmt.Errorf("since: %w", err)
+		}
+	}
+	if until != "" {
+		if r.Until, err = filter.ParseTime(until); err != nil {
+			return r, fmt.Errorf("until: %w", err)
+		}
+	}
+
+	return r, nil
+}
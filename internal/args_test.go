@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/output"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		argv    []string
+		wantErr string
+		assert  func(t *testing.T, a args)
+	}{
+		{name: "no limit", argv: nil, wantErr: "please provide limit"},
+		{name: "limit out of range", argv: []string{"-l=101"}, wantErr: "out of range"},
+		{name: "unknown flag", argv: []string{"-l=1", "-nope"}, wantErr: "not defined"},
+		{name: "unknown format", argv: []string{"-l=1", "-format=yaml"}, wantErr: "unknown format"},
+		{name: "template with format", argv: []string{"-l=1", "-format=json", "-template=x"}, wantErr: "-template"},
+		{name: "resume in watch mode", argv: []string{"-l=1", "-watch", "-resume=c.json"}, wantErr: "one-shot"},
+		{name: "last with since", argv: []string{"-l=1", "-last=1d", "-since=2024-01-01"}, wantErr: "-last"},
+		{name: "invalid log level", argv: []string{"-l=1", "-log-level=verbose"}, wantErr: "log-level"},
+		{name: "partial in watch mode", argv: []string{"-l=1", "-watch", "-partial"}, wantErr: "one-shot"},
+		{name: "unknown stop signal", argv: []string{"-l=1", "-stop-signals=KILL"}, wantErr: "unknown signal"},
+		{name: "USR1 stop signal", argv: []string{"-l=1", "-stop-signals=TERM,USR1"}, wantErr: "dumps the state"},
+		{name: "HUP stop signal in watch mode", argv: []string{"-l=1", "-watch", "-stop-signals=HUP"}, wantErr: "reloads"},
+		{
+			name: "serve defaults the limit",
+			argv: []string{"-serve"},
+			assert: func(t *testing.T, a args) {
+				assert.Equal(t, MaxLimit, a.limit)
+			},
+		},
+		{
+			name: "valid",
+			argv: []string{"-l=5", "-format=csv", "-resume=c.json", "-author=a, b"},
+			assert: func(t *testing.T, a args) {
+				assert.Equal(t, 5, a.limit)
+				assert.Equal(t, output.CSV, a.format)
+				assert.Equal(t, "c.json", a.checkpoint)
+				assert.Equal(t, []string{"a", "b"}, a.filter.Authors)
+				assert.Equal(t, map[string]string{"l": "5", "format": "csv", "resume": "c.json", "author": "a, b"}, a.params)
+			},
+		},
+		{
+			name: "stop signals",
+			argv: []string{"-l=1", "-stop-signals=sigterm, QUIT"},
+			assert: func(t *testing.T, a args) {
+				assert.Equal(t, []os.Signal{syscall.SIGTERM, syscall.SIGQUIT}, a.stopSignals)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parseArgs(tt.argv)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.assert(t, a)
+		})
+	}
+}
+
+func TestParseArgs_Help(t *testing.T) {
+	_, err := parseArgs([]string{"-h"})
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+}
+
+func TestArgs_Validate_BuiltInCode(t *testing.T) {
+	a := args{limit: 5, api: articlesapi.DefaultConfig()}
+	require.NoError(t, a.validate(), "settings without flags take the defaults")
+
+	a.errorFormat = "yaml"
+	assert.ErrorContains(t, a.validate(), "unknown error format")
+}
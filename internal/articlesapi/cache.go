@@ -0,0 +1,59 @@
+package articlesapi
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Cache keeps fetched pages between runs(watch/serve modes) for conditional
+	// requests(ETag/Last-Modified) and to skip requests of fresh pages at all.
+	Cache interface {
+		Get(page int) (CacheEntry, bool)
+		Put(page int, e CacheEntry)
+	}
+	CacheEntry struct {
+		Response     *Response
+		ETag         string
+		LastModified string
+		FetchedAt    time.Time
+	}
+	// MemoryCache is the in-process Cache, safe for concurrent use.
+	MemoryCache struct {
+		mu    sync.RWMutex
+		pages map[int]CacheEntry
+	}
+)
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{pages: make(map[int]CacheEntry)}
+}
+
+func (c *MemoryCache) Get(page int) (CacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.pages[page]
+	return e, ok
+}
+
+func (c *MemoryCache) Put(page int, e CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pages[page] = e
+}
+
+func (c *MemoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.pages)
+}
+
+// fresh reports whether the entry can be used without revalidation.
+func (e CacheEntry) fresh(ttl time.Duration) bool {
+	return ttl > 0 && time.Since(e.FetchedAt) < ttl
+}
+
+// validators reports whether upstream gave us anything for a conditional request.
+func (e CacheEntry) validators() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
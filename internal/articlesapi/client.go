@@ -3,12 +3,22 @@ package articlesapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
+
+	"articles-service/internal/metrics"
+	"articles-service/internal/report"
 )
 
 // todo: To optimize the number of rps to an external server,
@@ -20,30 +30,101 @@ const (
 	// rate limiting: the external server definitely having rate limit per ipAddress
 	// therefore it is better to be able to regulate it from our side as well
 	// to avoid possible ban (will explain).
-	// todo: possible to add run argument
+	// defaults, see Config
 	MaxRPSPerCurrentHost = 10.0
 	burstPerSecond       = 1
+	// retries of transient errors
+	maxAttempts    = 3
+	retryBaseDelay = 500 * time.Millisecond
+	timeout        = 60 * time.Second
+	// transport, connections per host are not limited: workers and the rate limiter bound them
+	headerTimeout       = 30 * time.Second
+	idleConnTimeout     = 60 * time.Second
+	maxIdleConnsPerHost = 16
 )
 
+var tracer = otel.Tracer("articles-service/internal/articlesapi")
+
+// Config of the client, zero fields keep the current values.
+type Config struct {
+	BaseURL string
+	// requests per second and the burst of the rate limiter
+	RPS   float64
+	Burst int
+	// attempts of the page fetch, the delay before the first retry doubles with every retry
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// timeout of the request including the body read
+	Timeout time.Duration
+	// wait for the response headers after the request is written
+	HeaderTimeout time.Duration
+	// idle keep-alive connections: per host and how long they are kept
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+	// connections per host including active ones, 0 - no limit
+	MaxConnsPerHost int
+}
+
 type Client struct {
 	logger     *zap.Logger
+	baseURL    string
 	httpClient *http.Client
 	limiter    *rate.Limiter
+	rec        *report.Recorder
+	metrics    *metrics.Metrics
+	cache      Cache
+	// pages younger than cacheTTL are served from the cache without requests
+	cacheTTL time.Duration
+	// retries of transient errors
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// StatusError is returned when upstream responds with non 200 status.
+type StatusError struct {
+	Page       int
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d for page %d", e.StatusCode, e.Page)
+}
+
+// RateLimited reports whether upstream kept responding 429 after all retries.
+func RateLimited(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests
+}
+
+// Unavailable reports whether upstream failed the request: network errors or unexpected statuses
+// except 429, cancellation is not a failure of upstream.
+func Unavailable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode != http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
 }
 
 func New(
 	logger *zap.Logger,
 ) *Client {
 	return &Client{
-		logger: logger,
+		logger:      logger.Named("client"),
+		baseURL:     baseURL,
+		maxAttempts: maxAttempts,
+		retryDelay:  retryBaseDelay,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout: timeout,
 			Transport: &http.Transport{
-				MaxConnsPerHost:       1,
-				MaxIdleConns:          1,
-				MaxIdleConnsPerHost:   1,
-				IdleConnTimeout:       60 * time.Second,
-				ResponseHeaderTimeout: 30 * time.Second,
+				MaxIdleConns:          maxIdleConnsPerHost,
+				MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+				IdleConnTimeout:       idleConnTimeout,
+				ResponseHeaderTimeout: headerTimeout,
 				ExpectContinueTimeout: 0,
 				ForceAttemptHTTP2:     true,
 			},
@@ -53,34 +134,209 @@ func New(
 	}
 }
 
+// DefaultConfig returns the config of the new client.
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:     baseURL,
+		RPS:         MaxRPSPerCurrentHost,
+		Burst:       burstPerSecond,
+		MaxAttempts: maxAttempts,
+		RetryDelay:  retryBaseDelay,
+		Timeout:     timeout,
+
+		HeaderTimeout:   headerTimeout,
+		MaxIdleConns:    maxIdleConnsPerHost,
+		IdleConnTimeout: idleConnTimeout,
+	}
+}
+
+// Configure applies the config, must be called before fetching.
+func (c *Client) Configure(cfg Config) {
+	if cfg.BaseURL != "" {
+		c.baseURL = cfg.BaseURL
+	}
+	if cfg.RPS > 0 {
+		c.limiter.SetLimit(rate.Limit(cfg.RPS))
+	}
+	if cfg.Burst > 0 {
+		c.limiter.SetBurst(cfg.Burst)
+	}
+	if cfg.MaxAttempts > 0 {
+		c.maxAttempts = cfg.MaxAttempts
+	}
+	if cfg.RetryDelay > 0 {
+		c.retryDelay = cfg.RetryDelay
+	}
+	if cfg.Timeout > 0 {
+		c.httpClient.Timeout = cfg.Timeout
+	}
+
+	tr := c.httpClient.Transport.(*http.Transport)
+	if cfg.HeaderTimeout > 0 {
+		tr.ResponseHeaderTimeout = cfg.HeaderTimeout
+	}
+	if cfg.MaxIdleConns > 0 {
+		// one upstream host
+		tr.MaxIdleConns, tr.MaxIdleConnsPerHost = cfg.MaxIdleConns, cfg.MaxIdleConns
+	}
+	if cfg.IdleConnTimeout > 0 {
+		tr.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		tr.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+}
+
+// SetRateLimit changes the rate limit, safe while fetching. Zero values keep the current ones as in Configure.
+func (c *Client) SetRateLimit(rps float64, burst int) {
+	if rps > 0 {
+		c.limiter.SetLimit(rate.Limit(rps))
+	}
+	if burst > 0 {
+		c.limiter.SetBurst(burst)
+	}
+}
+
+// Tokens returns the available tokens of the rate limiter.
+func (c *Client) Tokens() float64 {
+	return c.limiter.Tokens()
+}
+
+// SetBaseURL sets the articles endpoint, e.g. a mirror, must be called before fetching.
+func (c *Client) SetBaseURL(u string) {
+	c.baseURL = u
+}
+
+// SetMetrics sets the metrics kept across runs, must be called before fetching.
+func (c *Client) SetMetrics(m *metrics.Metrics) {
+	c.metrics = m
+}
+
+// SetRecorder sets the run statistics recorder, must be called before fetching.
+func (c *Client) SetRecorder(rec *report.Recorder) {
+	c.rec = rec
+}
+
+// SetCache enables the page cache, zero ttl means every cached page is revalidated
+// with a conditional request, must be called before fetching.
+func (c *Client) SetCache(cache Cache, ttl time.Duration) {
+	c.cache, c.cacheTTL = cache, ttl
+}
+
+// FetchPage fetches the page retrying transient errors(network, 429, 5xx)
+// with exponential backoff.
 func (c *Client) FetchPage(ctx context.Context, page int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "FetchPage", trace.WithAttributes(attribute.Int("page", page)))
+	defer span.End()
+
+	backoff := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := c.fetchPage(ctx, page)
+		if err == nil {
+			span.SetAttributes(attribute.Int("attempts", attempt))
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			c.rec.PageInterrupted()
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "fetch page canceled")
+			return nil, err
+		}
+		if attempt >= c.maxAttempts || !retryable(err) {
+			c.rec.PageFailed(page)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "fetch page failed")
+			return nil, err
+		}
+
+		c.rec.PageRetried()
+		c.metrics.Retry()
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("backoff", backoff.String()),
+			attribute.String("error", err.Error()),
+		))
+		c.logger.Warn("retrying page",
+			zap.Int("page", page), zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			c.rec.PageInterrupted()
+			span.RecordError(ctx.Err())
+			span.SetStatus(codes.Error, "fetch page canceled")
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) fetchPage(ctx context.Context, page int) (*Response, error) {
+	var cached CacheEntry
+	var hasCached bool
+	if c.cache != nil {
+		if cached, hasCached = c.cache.Get(page); hasCached && cached.fresh(c.cacheTTL) {
+			c.rec.CacheHit()
+			trace.SpanFromContext(ctx).AddEvent("cache_hit")
+			return cached.Response, nil
+		}
+	}
+
+	waitStart := time.Now()
 	if err := c.limiter.Wait(ctx); err != nil {
+		// the turn of the request is after the deadline, other requests keep running until it
+		if _, ok := ctx.Deadline(); ok && ctx.Err() == nil {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
+	c.metrics.LimiterWait(time.Since(waitStart))
 
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		fmt.Sprintf("%s?page=%d", baseURL, page), nil)
+		fmt.Sprintf("%s?page=%d", c.baseURL, page), nil)
 	if err != nil {
 		return nil, err
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if hasCached {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
 
+	c.rec.Request()
+	defer c.metrics.PageStarted()()
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		// todo: it case of network issues "keep alive":
-		// logs, retry with backoff,
-		// checking all possible errors to prevent fall of the app
-		// with errors.Is or errors.As including RestAPI errors
-		// like (4xx / 5xx / 429), ratelimit, panic recovery ...
+		c.metrics.Request(0)
+		// todo: checking all possible errors to prevent fall of the app
+		// with errors.Is or errors.As including RestAPI errors, panic recovery ...
 		c.logger.Error("external API error", zap.Error(err))
 
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.metrics.Request(resp.StatusCode)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
+	if resp.StatusCode == http.StatusNotModified && hasCached {
+		c.rec.CacheHit()
+		trace.SpanFromContext(ctx).AddEvent("cache_hit", trace.WithAttributes(attribute.Bool("revalidated", true)))
+		c.rec.PageFetched(time.Since(start))
+		c.metrics.PageLatency(time.Since(start))
+		cached.FetchedAt = time.Now()
+		c.cache.Put(page, cached)
+		return cached.Response, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d for page %d", resp.StatusCode, page)
+		return nil, &StatusError{Page: page, StatusCode: resp.StatusCode}
 	}
 
 	var apiResp Response
@@ -90,6 +346,36 @@ func (c *Client) FetchPage(ctx context.Context, page int) (*Response, error) {
 	if err = json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, fmt.Errorf("decode page %d: %w", page, err)
 	}
+	c.rec.PageFetched(time.Since(start))
+	c.metrics.PageLatency(time.Since(start))
+
+	if c.cache != nil {
+		e := CacheEntry{
+			Response:     &apiResp,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+		}
+		// nothing to revalidate with, the entry is useful only while fresh
+		if e.validators() || c.cacheTTL > 0 {
+			c.cache.Put(page, e)
+		}
+	}
 
 	return &apiResp, nil
 }
+
+// retryable reports whether the error is transient.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
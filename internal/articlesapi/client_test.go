@@ -0,0 +1,193 @@
+package articlesapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"articles-service/internal/report"
+)
+
+func TestClient_FetchPage(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      bool
+		wantRequests int32
+		wantRetried  int
+		wantFailed   int
+	}{
+		{name: "ok", statuses: []int{200}, wantRequests: 1},
+		{name: "retries 5xx", statuses: []int{503, 500, 200}, wantRequests: 3, wantRetried: 2},
+		{name: "retries 429", statuses: []int{429, 200}, wantRequests: 2, wantRetried: 1},
+		{name: "gives up after max attempts", statuses: []int{502, 502, 502, 200}, wantErr: true, wantRequests: 3, wantRetried: 2, wantFailed: 1},
+		{name: "doesn't retry 4xx", statuses: []int{404, 200}, wantErr: true, wantRequests: 1, wantFailed: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[requests.Add(1)-1]
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(`{"page":2,"total_pages":3,"data":[{"title":"a","num_comments":1}]}`))
+				}
+			}))
+			defer srv.Close()
+
+			rec := report.NewRecorder()
+			c := New(zap.NewNop())
+			c.baseURL = srv.URL
+			c.SetRecorder(rec)
+
+			resp, err := c.FetchPage(context.Background(), 2)
+			if tt.wantErr {
+				require.Error(t, err)
+				var statusErr *StatusError
+				assert.True(t, errors.As(err, &statusErr))
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 2, resp.Page)
+				require.Len(t, resp.Data, 1)
+			}
+
+			rep := rec.Report(nil)
+			assert.Equal(t, tt.wantRequests, requests.Load())
+			assert.Equal(t, int(tt.wantRequests), rep.Pages.Requests)
+			assert.Equal(t, tt.wantRetried, rep.Pages.Retried)
+			assert.Equal(t, tt.wantFailed, rep.Pages.Failed)
+		})
+	}
+}
+
+func TestClient_FetchPage_ContextCanceledDuringBackoff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := New(zap.NewNop())
+	c.baseURL = srv.URL
+	rec := report.NewRecorder()
+	c.SetRecorder(rec)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := c.FetchPage(ctx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the page didn't fail by upstream, it was stopped
+	rep := rec.Report(nil)
+	assert.Zero(t, rep.Pages.Failed)
+	assert.Empty(t, rep.Pages.FailedPages)
+	assert.Equal(t, 1, rep.Pages.Interrupted)
+}
+
+func TestClient_Configure_Transport(t *testing.T) {
+	c := New(zap.NewNop())
+	tr := c.httpClient.Transport.(*http.Transport)
+	// connections are not serialized by default
+	assert.Zero(t, tr.MaxConnsPerHost)
+
+	c.Configure(Config{HeaderTimeout: time.Second, MaxIdleConns: 4, IdleConnTimeout: time.Minute, MaxConnsPerHost: 8})
+	assert.Equal(t, time.Second, tr.ResponseHeaderTimeout)
+	assert.Equal(t, 4, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, tr.IdleConnTimeout)
+	assert.Equal(t, 8, tr.MaxConnsPerHost)
+
+	// zero fields keep the current values
+	c.Configure(Config{})
+	assert.Equal(t, time.Second, tr.ResponseHeaderTimeout)
+	assert.Equal(t, 8, tr.MaxConnsPerHost)
+}
+
+func TestClient_SetRateLimit(t *testing.T) {
+	c := New(zap.NewNop())
+	c.SetRateLimit(4, 2)
+	assert.Equal(t, rate.Limit(4), c.limiter.Limit())
+	assert.Equal(t, 2, c.limiter.Burst())
+
+	// zero burst would fail every request and zero rps stall them
+	c.SetRateLimit(0, 0)
+	assert.Equal(t, rate.Limit(4), c.limiter.Limit())
+	assert.Equal(t, 2, c.limiter.Burst())
+}
+
+func TestClient_FetchPage_HeaderTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	c := New(zap.NewNop())
+	c.baseURL = srv.URL
+	c.Configure(Config{MaxAttempts: 1, HeaderTimeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	_, err := c.FetchPage(context.Background(), 1)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClient_FetchPage_Cache(t *testing.T) {
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		etag          string
+		wantRequests  int32
+		wantCacheHits int
+	}{
+		{name: "revalidates with etag", etag: `"v1"`, wantRequests: 2, wantCacheHits: 1},
+		{name: "fresh entry skips request", ttl: time.Hour, etag: `"v1"`, wantRequests: 1, wantCacheHits: 1},
+		{name: "no validators no ttl", wantRequests: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if tt.etag != "" {
+					if r.Header.Get("If-None-Match") == tt.etag {
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+					w.Header().Set("ETag", tt.etag)
+				}
+				_, _ = w.Write([]byte(`{"page":1,"total_pages":1,"data":[{"title":"a","num_comments":1}]}`))
+			}))
+			defer srv.Close()
+
+			rec := report.NewRecorder()
+			c := New(zap.NewNop())
+			c.baseURL = srv.URL
+			c.SetRecorder(rec)
+			c.SetCache(NewMemoryCache(), tt.ttl)
+
+			for i := 0; i < 2; i++ {
+				resp, err := c.FetchPage(context.Background(), 1)
+				require.NoError(t, err)
+				require.Len(t, resp.Data, 1)
+			}
+
+			assert.Equal(t, tt.wantRequests, requests.Load())
+			assert.Equal(t, tt.wantCacheHits, rec.Report(nil).Pages.CacheHits)
+		})
+	}
+}
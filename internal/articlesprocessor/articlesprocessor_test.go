@@ -2,10 +2,13 @@ package articlesprocessor
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"articles-service/internal/articlesapi"
+	"articles-service/internal/filter"
+	"articles-service/internal/report"
 	"articles-service/internal/storage"
 
 	"github.com/stretchr/testify/assert"
@@ -128,7 +131,7 @@ func TestArticlesProcessor_processArticle(t *testing.T) {
 				storage: st,
 			}
 
-			err := p.processArticle(tt.input)
+			err := p.processArticle(tt.input, 1)
 			require.NoError(t, err)
 
 			names := st.TopArticlesNames()
@@ -174,11 +177,11 @@ func TestArticlesProcessor_sendPagesToProcess(t *testing.T) {
 			}
 
 			var g errgroup.Group
-			p.sendPagesToProcess(tt.pages, &g)
+			p.sendPagesToProcess(context.Background(), tt.pages, &g)
 
 			var got []int
 			for page := range p.in {
-				got = append(got, page)
+				got = append(got, page.Number)
 			}
 			if got == nil {
 				got = []int{}
@@ -219,7 +222,7 @@ func TestArticlesProcessor_sendPagesToProcess_IntegrationWithProducerStop(t *tes
 	})
 
 	pages := 3
-	p.sendPagesToProcess(pages, &g)
+	p.sendPagesToProcess(ctx, pages, &g)
 
 	for range p.in {
 	}
@@ -227,3 +230,139 @@ func TestArticlesProcessor_sendPagesToProcess_IntegrationWithProducerStop(t *tes
 	err := g.Wait()
 	require.NoError(t, err)
 }
+
+func TestArticlesProcessor_processArticle_Collectors(t *testing.T) {
+	logger := zap.NewNop()
+
+	st := storage.New(logger, 10)
+	domains := storage.NewAggregate(logger, func(a storage.Article) string { return a.URL })
+
+	p := &ArticlesProcessor{
+		logger:  logger,
+		limit:   10,
+		storage: st,
+	}
+	p.AddCollector(domains)
+
+	inputs := []*articlesapi.Article{
+		{Title: strPtr("with-url"), URL: "a.com", NumComments: intPtr(3)},
+		{Title: strPtr("with-story-url"), StoryURL: strPtr("b.com"), NumComments: intPtr(2)},
+		{Title: strPtr("url-preferred"), URL: "a.com", StoryURL: strPtr("b.com"), NumComments: intPtr(1)},
+		{Title: strPtr("skipped"), URL: "c.com"},
+	}
+	for _, in := range inputs {
+		require.NoError(t, p.processArticle(in, 1))
+	}
+
+	assert.Equal(t, []storage.Group{
+		{Key: "a.com", NumComments: 4, NumArticles: 2},
+		{Key: "b.com", NumComments: 2, NumArticles: 1},
+	}, domains.Top(10, storage.RankByComments))
+}
+
+func TestArticlesProcessor_processArticle_Filter(t *testing.T) {
+	logger := zap.NewNop()
+
+	st := storage.New(logger, 10)
+	chain, err := filter.Config{MinComments: 5}.Build()
+	require.NoError(t, err)
+
+	p := &ArticlesProcessor{
+		logger:  logger,
+		limit:   10,
+		storage: st,
+	}
+	p.SetFilter(chain)
+
+	require.NoError(t, p.processArticle(&articlesapi.Article{Title: strPtr("kept"), NumComments: intPtr(5)}, 1))
+	require.NoError(t, p.processArticle(&articlesapi.Article{Title: strPtr("filtered"), NumComments: intPtr(4)}, 1))
+
+	assert.Equal(t, []string{"kept"}, st.TopArticlesNames())
+	assert.Equal(t, map[string]uint64{"comments": 1}, chain.Rejected())
+}
+
+func TestArticlesProcessor_processArticle_Report(t *testing.T) {
+	logger := zap.NewNop()
+
+	st := storage.New(logger, 10)
+	chain, err := filter.Config{MinComments: 2}.Build()
+	require.NoError(t, err)
+	rec := report.NewRecorder()
+
+	p := &ArticlesProcessor{
+		logger:  logger,
+		limit:   10,
+		storage: st,
+	}
+	p.SetFilter(chain)
+	p.SetRecorder(rec)
+
+	inputs := []*articlesapi.Article{
+		{Title: strPtr("a"), Author: "x", CreatedAt: intPtr(1), NumComments: intPtr(5)},
+		{Title: strPtr("a"), Author: "x", CreatedAt: intPtr(1), NumComments: intPtr(6)},
+		{Title: strPtr("a"), Author: "y", CreatedAt: intPtr(1), NumComments: intPtr(4)},
+		{Title: strPtr("b"), NumComments: intPtr(1)},
+		{NumComments: intPtr(1)},
+		{Title: strPtr("c")},
+	}
+	for _, in := range inputs {
+		require.NoError(t, p.processArticle(in, 1))
+	}
+
+	assert.Equal(t, []string{"a", "a"}, st.TopArticlesNames())
+
+	rows := rec.Report(chain.Rejected()).Rows
+	assert.Equal(t, 6, rows.Seen)
+	assert.Equal(t, 2, rows.Accepted)
+	assert.Equal(t, map[report.DropReason]int{
+		report.DropDeduped:        1,
+		report.DropFiltered:       1,
+		report.DropNilTitle:       1,
+		report.DropNilNumComments: 1,
+	}, rows.Dropped)
+}
+
+func TestArticlesProcessor_processArticle_DuplicatesAcrossPages(t *testing.T) {
+	// the shifted rows come again on the other pages with updated comments
+	pages := map[int][]*articlesapi.Article{
+		1: {{Title: strPtr("a"), Author: "x", CreatedAt: intPtr(1), URL: "a.com", NumComments: intPtr(5)}},
+		2: {
+			{Title: strPtr("a"), Author: "x", CreatedAt: intPtr(1), URL: "b.com", NumComments: intPtr(7)},
+			{Title: strPtr("b"), Author: "y", CreatedAt: intPtr(2), URL: "b.com", NumComments: intPtr(3)},
+		},
+		3: {
+			{Title: strPtr("a"), Author: "x", CreatedAt: intPtr(1), URL: "c.com", NumComments: intPtr(7)},
+			{Title: strPtr("b"), Author: "y", CreatedAt: intPtr(2), URL: "c.com", NumComments: intPtr(3)},
+		},
+	}
+
+	for _, order := range [][]int{{1, 2, 3}, {3, 2, 1}, {2, 3, 1}, {3, 1, 2}} {
+		t.Run(fmt.Sprint(order), func(t *testing.T) {
+			logger := zap.NewNop()
+			st := storage.New(logger, 10)
+			domains := storage.NewAggregate(logger, func(a storage.Article) string { return a.URL })
+			rec := report.NewRecorder()
+			p := &ArticlesProcessor{
+				logger:  logger,
+				limit:   10,
+				storage: st,
+			}
+			p.AddCollector(domains)
+			p.SetRecorder(rec)
+
+			for _, number := range order {
+				for _, a := range pages[number] {
+					require.NoError(t, p.processArticle(a, number))
+				}
+			}
+
+			// the most comments, then the lowest page
+			top := st.TopArticles()
+			require.Len(t, top, 2)
+			assert.Equal(t, storage.Article{Name: "a", NumComments: 7, Author: "x", URL: "b.com", CreatedAt: time.Unix(1, 0).UTC()}, top[0])
+			assert.Equal(t, storage.Article{Name: "b", NumComments: 3, Author: "y", URL: "b.com", CreatedAt: time.Unix(2, 0).UTC()}, top[1])
+			assert.Equal(t, []storage.Group{{Key: "b.com", NumComments: 10, NumArticles: 2}}, domains.Top(10, storage.RankByComments))
+			assert.Equal(t, map[report.DropReason]int{report.DropDeduped: 3}, rec.Report(nil).Rows.Dropped)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package articlesprocessor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal/output"
+)
+
+const checkpointVersion = 2
+
+// ErrCheckpointDrift means upstream changed since the checkpoint,
+// pages are shifted then and the crawl must start over.
+var ErrCheckpointDrift = errors.New("upstream changed since the checkpoint")
+
+type (
+	// Checkpoint is the progress of the interrupted crawl:
+	// completed pages and the state built from them.
+	Checkpoint struct {
+		Version    int       `json:"version"`
+		UpdatedAt  time.Time `json:"updated_at"`
+		Limit      int       `json:"limit"`
+		TotalPages int       `json:"total_pages"`
+		Total      int       `json:"total"`
+		Completed  []int     `json:"completed"`
+		// pages failed after all retries, informational: every not completed page is refetched
+		Failed     []int             `json:"failed,omitempty"`
+		Storage    json.RawMessage   `json:"storage"`
+		Collectors []json.RawMessage `json:"collectors"`
+		Seen       []seenRow         `json:"seen,omitempty"`
+	}
+	// Checkpointer is a Collector which state is saved into checkpoints,
+	// state of other collectors is lost on resume.
+	Checkpointer interface {
+		Collector
+		MarshalState() ([]byte, error)
+		RestoreState(data []byte) error
+	}
+)
+
+// LoadCheckpoint reads the checkpoint written by the processor.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cp Checkpoint
+	if err = json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("%s: unsupported checkpoint version %d", path, cp.Version)
+	}
+
+	return &cp, nil
+}
+
+// SetCheckpoint enables writing the crawl progress to the file at most every interval
+// and when the crawl stops unfinished, the file is removed after the successful crawl.
+// Must be called before TopArticles.
+func (p *ArticlesProcessor) SetCheckpoint(path string, interval time.Duration) {
+	p.checkpointPath, p.checkpointInterval = path, interval
+}
+
+// Resume continues the crawl from the checkpoint in the next TopArticles,
+// collectors must be the same as in the checkpointed crawl.
+func (p *ArticlesProcessor) Resume(cp *Checkpoint) error {
+	if cp.Limit != p.limit {
+		return fmt.Errorf("checkpoint limit %d doesn't match %d", cp.Limit, p.limit)
+	}
+	p.resume = cp
+	return nil
+}
+
+// restore applies the resumed checkpoint to the fresh per run state.
+func (p *ArticlesProcessor) restore() error {
+	cp := p.resume
+	if cp == nil {
+		return nil
+	}
+
+	if len(cp.Collectors) != len(p.collectors) {
+		return fmt.Errorf("checkpoint has %d collectors, got %d: resume with the same flags", len(cp.Collectors), len(p.collectors))
+	}
+	if err := p.storage.RestoreState(cp.Storage); err != nil {
+		return fmt.Errorf("restore storage: %w", err)
+	}
+	for i, c := range p.collectors {
+		if cc, ok := c.(Checkpointer); ok && cp.Collectors[i] != nil {
+			if err := cc.RestoreState(cp.Collectors[i]); err != nil {
+				return fmt.Errorf("restore collector %d: %w", i, err)
+			}
+		}
+	}
+
+	p.seen = make(map[articleKey]seenRow, len(cp.Seen))
+	for _, row := range cp.Seen {
+		p.seen[keyOf(row.Article)] = row
+	}
+	p.skip = make(map[int]struct{}, len(cp.Completed))
+	p.completed = make(map[int]struct{}, len(cp.Completed))
+	for _, page := range cp.Completed {
+		p.skip[page] = struct{}{}
+		p.completed[page] = struct{}{}
+	}
+	p.progress.done.Store(int64(len(cp.Completed)))
+
+	p.logger.Info("resuming the crawl from the checkpoint",
+		zap.Int("completed_pages", len(cp.Completed)),
+		zap.Int("total_pages", cp.TotalPages),
+		zap.Time("updated_at", cp.UpdatedAt),
+	)
+
+	return nil
+}
+
+// checkDrift verifies upstream totals against the resumed checkpoint.
+func (p *ArticlesProcessor) checkDrift(totalPages, total int) error {
+	cp := p.resume
+	if cp == nil || (cp.TotalPages == totalPages && cp.Total == total) {
+		return nil
+	}
+	return fmt.Errorf("%w: total pages %d -> %d, total %d -> %d", ErrCheckpointDrift, cp.TotalPages, totalPages, cp.Total, total)
+}
+
+// pageCompleted marks the processed page, called by the consumer only.
+func (p *ArticlesProcessor) pageCompleted(page int) {
+	if p.checkpointPath == "" {
+		return
+	}
+	if p.completed == nil {
+		p.completed = make(map[int]struct{})
+	}
+	p.completed[page] = struct{}{}
+
+	if time.Since(p.checkpointAt) < p.checkpointInterval {
+		return
+	}
+	if err := p.writeCheckpoint(); err != nil {
+		p.logger.Error("cannot write the checkpoint", zap.Error(err))
+	}
+}
+
+// pageFailed records the page failed after all retries, called by producers.
+func (p *ArticlesProcessor) pageFailed(page int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failed = append(p.failed, page)
+}
+
+// writeCheckpoint must not run concurrently with the consumer.
+func (p *ArticlesProcessor) writeCheckpoint() error {
+	cp := Checkpoint{
+		Version:    checkpointVersion,
+		UpdatedAt:  time.Now().UTC(),
+		Limit:      p.limit,
+		TotalPages: p.totalPages,
+		Total:      p.total,
+		Completed:  make([]int, 0, len(p.completed)),
+		Collectors: make([]json.RawMessage, len(p.collectors)),
+		Seen:       make([]seenRow, 0, len(p.seen)),
+	}
+	for page := range p.completed {
+		cp.Completed = append(cp.Completed, page)
+	}
+	slices.Sort(cp.Completed)
+
+	p.mu.Lock()
+	cp.Failed = slices.Clone(p.failed)
+	p.mu.Unlock()
+	slices.Sort(cp.Failed)
+
+	var err error
+	if cp.Storage, err = p.storage.MarshalState(); err != nil {
+		return err
+	}
+	for i, c := range p.collectors {
+		if cc, ok := c.(Checkpointer); ok {
+			if cp.Collectors[i], err = cc.MarshalState(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, row := range p.seen {
+		cp.Seen = append(cp.Seen, row)
+	}
+
+	err = output.WriteFile(p.checkpointPath, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(cp)
+	})
+	if err != nil {
+		return err
+	}
+	p.checkpointAt = time.Now()
+
+	return nil
+}
+
+// finishCheckpoint keeps the progress of the unfinished crawl
+// and removes the checkpoint of the finished one.
+func (p *ArticlesProcessor) finishCheckpoint(finished bool) {
+	if p.checkpointPath == "" {
+		return
+	}
+
+	if finished {
+		if err := os.Remove(p.checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			p.logger.Error("cannot remove the checkpoint", zap.Error(err))
+		}
+		return
+	}
+
+	// nothing fetched, e.g. the first page failed: keep the previous checkpoint
+	if p.totalPages == 0 {
+		return
+	}
+	if err := p.writeCheckpoint(); err != nil {
+		p.logger.Error("cannot write the checkpoint", zap.Error(err))
+		return
+	}
+	p.logger.Info("crawl progress saved to the checkpoint",
+		zap.String("path", p.checkpointPath),
+		zap.Int("completed_pages", len(p.completed)),
+		zap.Int("total_pages", p.totalPages),
+	)
+}
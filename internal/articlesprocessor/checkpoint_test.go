@@ -0,0 +1,104 @@
+package articlesprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/storage"
+)
+
+func TestArticlesProcessor_Checkpoint(t *testing.T) {
+	logger := zap.NewNop()
+	path := filepath.Join(t.TempDir(), "crawl.checkpoint")
+
+	newProcessor := func() (*ArticlesProcessor, *storage.Aggregate) {
+		authors := storage.NewAggregate(logger, func(a storage.Article) string { return a.Author })
+		p := &ArticlesProcessor{
+			logger:  logger,
+			limit:   2,
+			storage: storage.New(logger, 2),
+		}
+		p.SetCollectors(authors)
+		p.SetCheckpoint(path, 0)
+		return p, authors
+	}
+
+	// interrupted crawl: pages 1 and 3 of 4 processed, page 2 failed
+	p, _ := newProcessor()
+	p.reset()
+	p.totalPages, p.total = 4, 8
+	pages := map[int][]*articlesapi.Article{
+		1: {{Title: strPtr("a"), Author: "x", NumComments: intPtr(5)}, {Title: strPtr("b"), Author: "y", NumComments: intPtr(1)}},
+		3: {{Title: strPtr("c"), Author: "x", NumComments: intPtr(3)}},
+	}
+	for _, number := range []int{1, 3} {
+		for _, a := range pages[number] {
+			require.NoError(t, p.processArticle(a, number))
+		}
+		p.pageCompleted(number)
+	}
+	p.pageFailed(2)
+	p.finishCheckpoint(false)
+
+	cp, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, cp.Completed)
+	assert.Equal(t, []int{2}, cp.Failed)
+	assert.Equal(t, 4, cp.TotalPages)
+	assert.Equal(t, 8, cp.Total)
+
+	// resumed crawl gets the state back and fetches only pages 2 and 4
+	resumed, authors := newProcessor()
+	require.NoError(t, resumed.Resume(cp))
+	resumed.reset()
+	require.NoError(t, resumed.restore())
+
+	resumed.in = make(InChan, 4)
+	var g errgroup.Group
+	resumed.sendPagesToProcess(context.Background(), 4, &g)
+	require.NoError(t, g.Wait())
+	var sent []int
+	for page := range resumed.in {
+		sent = append(sent, page.Number)
+	}
+	assert.Equal(t, []int{4, 2}, sent)
+
+	// the same row of the shifted page is deduped
+	require.NoError(t, resumed.processArticle(&articlesapi.Article{Title: strPtr("c"), Author: "x", NumComments: intPtr(3)}, 2))
+	require.NoError(t, resumed.processArticle(&articlesapi.Article{Title: strPtr("d"), Author: "y", NumComments: intPtr(4)}, 2))
+
+	assert.Equal(t, []string{"a", "d"}, resumed.storage.TopArticlesNames())
+	assert.Equal(t, []storage.Group{
+		{Key: "x", NumComments: 8, NumArticles: 2},
+		{Key: "y", NumComments: 5, NumArticles: 2},
+	}, authors.Top(10, storage.RankByComments))
+
+	// finished crawl removes the checkpoint
+	resumed.finishCheckpoint(true)
+	_, err = LoadCheckpoint(path)
+	require.Error(t, err)
+}
+
+func TestArticlesProcessor_Resume_Mismatch(t *testing.T) {
+	logger := zap.NewNop()
+	p := &ArticlesProcessor{logger: logger, limit: 2, storage: storage.New(logger, 2)}
+
+	require.Error(t, p.Resume(&Checkpoint{Limit: 3}))
+
+	require.NoError(t, p.Resume(&Checkpoint{Limit: 2, TotalPages: 4, Total: 8, Collectors: make([]json.RawMessage, 1)}))
+	p.reset()
+	require.Error(t, p.restore(), "collectors of the checkpoint don't match")
+
+	err := p.checkDrift(5, 10)
+	assert.True(t, errors.Is(err, ErrCheckpointDrift))
+	assert.NoError(t, p.checkDrift(4, 8))
+}
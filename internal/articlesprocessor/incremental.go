@@ -0,0 +1,221 @@
+package articlesprocessor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/output"
+)
+
+const incrementalVersion = 1
+
+type (
+	// IncrementalState keeps pages of the last successful crawl with their fingerprints,
+	// the next incremental crawl refetches only pages which could have changed since.
+	IncrementalState struct {
+		Version    int               `json:"version"`
+		UpdatedAt  time.Time         `json:"updated_at"`
+		Total      int               `json:"total"`
+		TotalPages int               `json:"total_pages"`
+		PerPage    int               `json:"per_page"`
+		Pages      map[int]PageState `json:"pages"`
+	}
+	// PageState is the fingerprint(hash of the data and upstream total) and the data of the page.
+	PageState struct {
+		Hash  string               `json:"hash"`
+		Total int                  `json:"total"`
+		Data  articlesapi.Articles `json:"data"`
+	}
+)
+
+// SetIncremental enables the incremental crawl with the state kept in the file,
+// the state is updated after every successful crawl. Must be called before TopArticles.
+func (p *ArticlesProcessor) SetIncremental(path string) {
+	p.incrementalPath = path
+}
+
+// LoadIncrementalState reads the state, nil without error if there is no state yet.
+func LoadIncrementalState(path string) (*IncrementalState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st IncrementalState
+	if err = json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Version != incrementalVersion {
+		return nil, fmt.Errorf("%s: unsupported state version %d", path, st.Version)
+	}
+
+	return &st, nil
+}
+
+// loadIncremental loads the previous state, a broken one means the full crawl.
+func (p *ArticlesProcessor) loadIncremental() {
+	if p.incrementalPath == "" {
+		return
+	}
+
+	p.pages = make(map[int]PageState)
+	prev, err := LoadIncrementalState(p.incrementalPath)
+	if err != nil {
+		p.logger.Warn("cannot load the incremental state, running the full crawl", zap.Error(err))
+		return
+	}
+	p.prevState = prev
+}
+
+// planIncremental reuses pages of the previous crawl which couldn't have changed.
+// Upstream is expected to append new rows to the last pages: the first page must be the same,
+// pages before the first new row are reused, the last page is probed when totals are the same.
+// Must be called before the pipeline goroutines start, pages are processed right away.
+func (p *ArticlesProcessor) planIncremental(ctx context.Context, first *articlesapi.Response) error {
+	if p.incrementalPath == "" {
+		return nil
+	}
+	p.keepPage(1, first.Total, first.Data)
+
+	prev := p.prevState
+	full := func(reason string) error {
+		p.logger.Info("running the full crawl", zap.String("reason", reason))
+		return nil
+	}
+	if prev != nil {
+		if err := prev.check(); err != nil {
+			p.logger.Warn("broken incremental state, running the full crawl", zap.Error(err))
+			return nil
+		}
+	}
+	switch {
+	case prev == nil:
+		return full("no previous state")
+	case first.PerPage != prev.PerPage:
+		return full("per page changed")
+	case first.Total < prev.Total:
+		return full("total decreased")
+	case prev.Pages[1].Hash != p.pages[1].Hash:
+		return full("first page changed")
+	}
+
+	last := first.TotalPages
+	// the page with the first new row and the rest could have changed
+	changedFrom := prev.Total/prev.PerPage + 1
+	if first.Total == prev.Total && last == prev.TotalPages {
+		changedFrom = last
+	}
+	changedFrom = max(changedFrom, 2)
+
+	if last > 1 {
+		resp, err := p.articlesAPI.FetchPage(ctx, last)
+		if err != nil {
+			return err
+		}
+		if resp != nil {
+			if err = p.processPage(Page{Number: last, Articles: resp.Data}, first.Total); err != nil {
+				return err
+			}
+			p.skip[last] = struct{}{}
+
+			if first.Total == prev.Total && prev.Pages[last].Hash != p.pages[last].Hash {
+				return full("last page changed")
+			}
+		}
+	}
+
+	reused := 0
+	for page := 2; page < changedFrom; page++ {
+		st, ok := prev.Pages[page]
+		if !ok {
+			continue
+		}
+		if err := p.processPage(Page{Number: page, Articles: st.Data}, st.Total); err != nil {
+			return err
+		}
+		p.skip[page] = struct{}{}
+		reused++
+	}
+	p.rec.PagesReused(reused)
+
+	p.logger.Info("running the incremental crawl",
+		zap.Int("reused_pages", reused),
+		zap.Int("fetched_pages", last-reused),
+		zap.Int("total_pages", last),
+	)
+
+	return nil
+}
+
+// check validates the totals of the state, a hand edited or truncated file mustn't break the plan.
+func (st *IncrementalState) check() error {
+	if st.PerPage <= 0 {
+		return fmt.Errorf("invalid per page %d", st.PerPage)
+	}
+	if st.Total < 0 || st.TotalPages != (st.Total+st.PerPage-1)/st.PerPage {
+		return fmt.Errorf("total pages %d don't match total %d of %d per page", st.TotalPages, st.Total, st.PerPage)
+	}
+	return nil
+}
+
+// processPage processes the page outside of the consumer, before the pipeline starts.
+func (p *ArticlesProcessor) processPage(page Page, total int) error {
+	for _, a := range page.Articles {
+		if err := p.processArticle(a, page.Number); err != nil {
+			return err
+		}
+	}
+	p.keepPage(page.Number, total, page.Articles)
+	p.progress.done.Add(1)
+	return nil
+}
+
+// keepPage stores the page for the next incremental crawl, called by the consumer only.
+func (p *ArticlesProcessor) keepPage(page, total int, data articlesapi.Articles) {
+	if p.incrementalPath == "" {
+		return
+	}
+	p.pages[page] = PageState{Hash: pageHash(data), Total: total, Data: data}
+}
+
+// saveIncremental replaces the state with pages of the successful crawl.
+func (p *ArticlesProcessor) saveIncremental() {
+	if p.incrementalPath == "" {
+		return
+	}
+
+	st := IncrementalState{
+		Version:    incrementalVersion,
+		UpdatedAt:  time.Now().UTC(),
+		Total:      p.total,
+		TotalPages: p.totalPages,
+		PerPage:    p.perPage,
+		Pages:      p.pages,
+	}
+	err := output.WriteFile(p.incrementalPath, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(st)
+	})
+	if err != nil {
+		p.logger.Error("cannot save the incremental state", zap.Error(err))
+	}
+}
+
+func pageHash(data articlesapi.Articles) string {
+	// marshaling of structs is deterministic
+	b, _ := json.Marshal(data)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
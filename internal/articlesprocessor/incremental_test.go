@@ -0,0 +1,181 @@
+package articlesprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/report"
+	"articles-service/internal/storage"
+)
+
+// upstream serves rows by pages of perPage like the articles api.
+type upstream struct {
+	mu      sync.Mutex
+	perPage int
+	rows    []int // comments of rows, the title is the row index
+	fetched []int
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	u.fetched = append(u.fetched, page)
+
+	resp := articlesapi.Response{
+		Page:       page,
+		PerPage:    u.perPage,
+		Total:      len(u.rows),
+		TotalPages: (len(u.rows) + u.perPage - 1) / u.perPage,
+	}
+	for i := (page - 1) * u.perPage; i < min(page*u.perPage, len(u.rows)); i++ {
+		resp.Data = append(resp.Data, &articlesapi.Article{Title: strPtr("row " + strconv.Itoa(i)), NumComments: intPtr(u.rows[i])})
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (u *upstream) reset(rows ...int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.rows = append(u.rows[:0], rows...)
+	u.fetched = nil
+}
+
+func (u *upstream) fetchedPages() map[int]int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	res := make(map[int]int)
+	for _, p := range u.fetched {
+		res[p]++
+	}
+	return res
+}
+
+func TestArticlesProcessor_Incremental(t *testing.T) {
+	logger := zap.NewNop()
+	up := &upstream{perPage: 2}
+	srv := httptest.NewServer(up)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "incremental.json")
+	p := New(logger, 3, storage.New(logger, 3))
+	p.articlesAPI.SetBaseURL(srv.URL)
+	p.SetIncremental(path)
+
+	run := func() ([]string, report.Pages) {
+		rec := report.NewRecorder()
+		p.SetRecorder(rec)
+		top, err := p.TopArticles(context.Background())
+		require.NoError(t, err)
+		names := make([]string, len(top))
+		for i, a := range top {
+			names[i] = a.Name
+		}
+		return names, rec.Report(nil).Pages
+	}
+
+	tests := []struct {
+		name        string
+		rows        []int
+		wantTop     []string
+		wantFetched map[int]int
+		wantReused  int
+	}{
+		{
+			name:        "first run is full",
+			rows:        []int{1, 9, 2, 8, 3, 7, 4},
+			wantTop:     []string{"row 1", "row 3", "row 5"},
+			wantFetched: map[int]int{1: 1, 2: 1, 3: 1, 4: 1},
+		},
+		{
+			name:        "nothing changed: first and last pages are probed",
+			rows:        []int{1, 9, 2, 8, 3, 7, 4},
+			wantTop:     []string{"row 1", "row 3", "row 5"},
+			wantFetched: map[int]int{1: 1, 4: 1},
+			wantReused:  2,
+		},
+		{
+			name:        "appended rows: pages from the old last one are fetched",
+			rows:        []int{1, 9, 2, 8, 3, 7, 4, 10, 1, 20},
+			wantTop:     []string{"row 9", "row 7", "row 1"},
+			wantFetched: map[int]int{1: 1, 4: 1, 5: 1},
+			wantReused:  2,
+		},
+		{
+			name:        "first page changed: full crawl",
+			rows:        []int{5, 9, 2, 8, 3, 7, 4, 10, 1, 20},
+			wantTop:     []string{"row 9", "row 7", "row 1"},
+			wantFetched: map[int]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1},
+		},
+		{
+			name:        "last page changed with the same totals: full crawl",
+			rows:        []int{5, 9, 2, 8, 3, 7, 4, 10, 1, 30},
+			wantTop:     []string{"row 9", "row 7", "row 1"},
+			wantFetched: map[int]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1},
+		},
+	}
+
+	// runs depend on the state of the previous ones
+	for _, tt := range tests {
+		up.reset(tt.rows...)
+		top, pages := run()
+		assert.Equal(t, tt.wantTop, top, tt.name)
+		assert.Equal(t, tt.wantFetched, up.fetchedPages(), tt.name)
+		assert.Equal(t, tt.wantReused, pages.Reused, tt.name)
+	}
+
+	st, err := LoadIncrementalState(path)
+	require.NoError(t, err)
+	assert.Equal(t, 10, st.Total)
+	assert.Len(t, st.Pages, 5)
+}
+
+func TestArticlesProcessor_Incremental_BrokenState(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+	}{
+		{name: "zero per page", state: `{"version":1,"total":4,"total_pages":2,"per_page":0,"pages":{}}`},
+		{name: "total pages mismatch", state: `{"version":1,"total":4,"total_pages":9,"per_page":2,"pages":{}}`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zap.NewNop()
+			up := &upstream{perPage: 2}
+			up.reset(1, 9, 2, 8)
+			srv := httptest.NewServer(up)
+			defer srv.Close()
+
+			path := filepath.Join(t.TempDir(), "incremental.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.state), 0o644))
+
+			p := New(logger, 2, storage.New(logger, 2))
+			p.articlesAPI.SetBaseURL(srv.URL)
+			p.SetIncremental(path)
+
+			top, err := p.TopArticles(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, top, 2)
+			assert.Equal(t, map[int]int{1: 1, 2: 1}, up.fetchedPages(), "full crawl")
+
+			st, err := LoadIncrementalState(path)
+			require.NoError(t, err)
+			assert.Equal(t, 2, st.PerPage, "the state is replaced")
+		})
+	}
+}
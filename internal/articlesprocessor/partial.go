@@ -0,0 +1,74 @@
+package articlesprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrPartial matches PartialError with errors.Is.
+var ErrPartial = errors.New("partial result")
+
+// PartialError is returned by TopArticles with the top collected before the crawl
+// was interrupted(signal, deadline) when partial results are enabled.
+type PartialError struct {
+	PagesDone  int
+	TotalPages int
+	// why the crawl was interrupted
+	Cause error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("partial result, %d of %d pages: %v", e.PagesDone, e.TotalPages, e.Cause)
+}
+
+func (e *PartialError) Unwrap() error { return e.Cause }
+
+func (e *PartialError) Is(target error) bool { return target == ErrPartial }
+
+// SetPartial makes TopArticles return the top collected so far on interrupt:
+// new pages are not requested, in-flight ones are drained for up to drain(0 - no limit),
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetPartial(enabled bool, drain time.Duration) {
+	p.partial, p.drainTimeout = enabled, drain
+}
+
+// drainContext returns the context of the pipeline surviving the cancellation of parent
+// for the drain timeout, in-flight pages are finished meanwhile.
+func (p *ArticlesProcessor) drainContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, func() {
+		p.logger.Warn("interrupted, draining in-flight pages", zap.Duration("drain_timeout", p.drainTimeout))
+		if p.drainTimeout > 0 {
+			time.AfterFunc(p.drainTimeout, func() {
+				cancel(fmt.Errorf("drain timeout: %w", context.Cause(parent)))
+			})
+		}
+	})
+
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// partialResult reports whether the interrupted crawl returns the collected top,
+// err is the error of the drained pipeline.
+func (p *ArticlesProcessor) partialResult(parent, work context.Context, err error) bool {
+	if !p.partial || parent.Err() == nil {
+		return false
+	}
+	// pipeline errors caused by the drain timeout
+	return err == nil || work.Err() != nil
+}
+
+func (p *ArticlesProcessor) partialError(parent context.Context) *PartialError {
+	return &PartialError{
+		PagesDone:  int(p.progress.done.Load()),
+		TotalPages: int(p.progress.total.Load()),
+		Cause:      context.Cause(parent),
+	}
+}
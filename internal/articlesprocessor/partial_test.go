@@ -0,0 +1,75 @@
+package articlesprocessor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"articles-service/internal/storage"
+)
+
+func TestArticlesProcessor_Partial(t *testing.T) {
+	logger := zap.NewNop()
+	up := &upstream{perPage: 1, rows: []int{1, 2, 3}}
+	// the page 3 is requested first and is in flight when the crawl is interrupted
+	inFlight, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "3" {
+			close(inFlight)
+			<-release
+		}
+		up.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	p := New(logger, 3, storage.New(logger, 3))
+	p.articlesAPI.SetBaseURL(srv.URL)
+	p.SetWorkers(1)
+	p.SetPartial(true, time.Minute)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		<-inFlight
+		cancel(errors.New("interrupt"))
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	top, err := p.TopArticles(ctx)
+	var partial *PartialError
+	require.ErrorAs(t, err, &partial)
+	assert.True(t, errors.Is(err, ErrPartial))
+	assert.EqualError(t, partial.Cause, "interrupt")
+	assert.Equal(t, 2, partial.PagesDone)
+	assert.Equal(t, 3, partial.TotalPages)
+
+	var names []string
+	for _, a := range top {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"row 2", "row 0"}, names)
+	assert.NotContains(t, up.fetchedPages(), 2)
+}
+
+func TestArticlesProcessor_Partial_Disabled(t *testing.T) {
+	logger := zap.NewNop()
+	srv := httptest.NewServer(&upstream{perPage: 1, rows: []int{1, 2, 3}})
+	defer srv.Close()
+
+	p := New(logger, 3, storage.New(logger, 3))
+	p.articlesAPI.SetBaseURL(srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	top, err := p.TopArticles(ctx)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrPartial))
+	assert.Nil(t, top)
+}
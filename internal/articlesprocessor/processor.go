@@ -4,14 +4,25 @@ import (
 	"context"
 	"errors"
 	"runtime"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
 	"articles-service/internal/articlesapi"
+	"articles-service/internal/filter"
+	"articles-service/internal/metrics"
+	"articles-service/internal/report"
 	"articles-service/internal/storage"
 )
 
+var tracer = otel.Tracer("articles-service/internal/articlesprocessor")
+
 type (
 	ArticlesProcessor struct {
 		logger      *zap.Logger
@@ -20,9 +31,70 @@ type (
 		in          InChan
 		out         OutChan
 		storage     *storage.Storage
+		collectors  []Collector
+		filter      *filter.Chain
+		rec         *report.Recorder
+		metrics     *metrics.Metrics
+		// page fetchers, 0 - 2 per CPU
+		workers int
+		// partial results on interrupt, see partial.go
+		partial      bool
+		drainTimeout time.Duration
+		// live progress, see state.go
+		progress progress
+		// the same row may come twice when upstream shifts pages during the crawl
+		seen map[articleKey]seenRow
+
+		// checkpoints, see checkpoint.go
+		checkpointPath     string
+		checkpointInterval time.Duration
+		checkpointAt       time.Time
+		resume             *Checkpoint
+		// pages completed by the resumed crawl, read only during the run
+		skip map[int]struct{}
+		// processed pages, consumer only
+		completed         map[int]struct{}
+		totalPages, total int
+		mu                sync.Mutex
+		failed            []int
+
+		// incremental crawl, see incremental.go
+		incrementalPath string
+		prevState       *IncrementalState
+		// pages of the current crawl, consumer only
+		pages   map[int]PageState
+		perPage int
+	}
+	articleKey struct {
+		Name      string
+		Author    string
+		CreatedAt int64
+	}
+	// seenRow is the kept row of the key and the page it came from.
+	seenRow struct {
+		Article storage.Article `json:"a"`
+		Page    int             `json:"p"`
+	}
+	// PageRequest is the page to fetch, Ctx carries the span the page belongs to.
+	PageRequest struct {
+		Ctx    context.Context
+		Number int
 	}
-	OutChan = chan articlesapi.Articles
-	InChan  = chan int
+	// Page is the fetched page of articles, Ctx carries the span of the page.
+	Page struct {
+		Ctx      context.Context
+		Number   int
+		Articles articlesapi.Articles
+	}
+	// Collector receives every accepted article in addition to the top storage
+	// (leaderboards, aggregates etc.), must be safe for concurrent use.
+	// Replace swaps the inserted article for its better duplicate.
+	Collector interface {
+		Insert(a storage.Article)
+		Replace(old, a storage.Article)
+	}
+	OutChan = chan Page
+	InChan  = chan PageRequest
 )
 
 func New(
@@ -31,49 +103,190 @@ func New(
 	storage *storage.Storage,
 ) *ArticlesProcessor {
 	return &ArticlesProcessor{
-		logger:      logger,
+		logger:      logger.Named("processor"),
 		limit:       limit,
 		articlesAPI: articlesapi.New(logger),
-		// small buffer to avoid potential blocking
-		// "Rely on metrics, not guesses."
-		out:     make(OutChan, articlesapi.MaxRPSPerCurrentHost),
-		in:      make(InChan, articlesapi.MaxRPSPerCurrentHost),
-		storage: storage,
+		storage:     storage,
 	}
 }
 
-func (p *ArticlesProcessor) TopArticles(ctx context.Context) ([]string, error) {
-	g, ctx := errgroup.WithContext(ctx)
-	if err := p.runPipeline(ctx, g); err != nil {
+// AddCollector registers an additional consumer of the processed articles,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) AddCollector(c Collector) {
+	p.collectors = append(p.collectors, c)
+}
+
+// SetCollectors replaces all collectors, e.g. with fresh ones for the next run,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetCollectors(cs ...Collector) {
+	p.collectors = cs
+}
+
+// SetFilter sets the filter stage running before storage insertion,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetFilter(c *filter.Chain) {
+	p.filter = c
+}
+
+// SetRecorder sets the run statistics recorder for the processor and the client,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetRecorder(rec *report.Recorder) {
+	p.rec = rec
+	if p.articlesAPI != nil {
+		p.articlesAPI.SetRecorder(rec)
+	}
+}
+
+// SetMetrics sets the metrics of the processor, the client and the top storage,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetMetrics(m *metrics.Metrics) {
+	p.metrics = m
+	if p.articlesAPI != nil {
+		p.articlesAPI.SetMetrics(m)
+	}
+	if p.storage != nil {
+		p.storage.SetMetrics(m)
+	}
+}
+
+// SetCache enables the page cache of the client kept between runs,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetCache(cache articlesapi.Cache, ttl time.Duration) {
+	p.articlesAPI.SetCache(cache, ttl)
+}
+
+// SetBaseURL sets the articles endpoint of the client, e.g. a mirror,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetBaseURL(u string) {
+	p.articlesAPI.SetBaseURL(u)
+}
+
+// SetClientConfig configures the client, must be called before TopArticles.
+func (p *ArticlesProcessor) SetClientConfig(cfg articlesapi.Config) {
+	p.articlesAPI.Configure(cfg)
+}
+
+// SetRateLimit changes the rate limit of the client, safe during the run.
+func (p *ArticlesProcessor) SetRateLimit(rps float64, burst int) {
+	p.articlesAPI.SetRateLimit(rps, burst)
+}
+
+// SetLimit changes the size of the top, must be called before TopArticles.
+func (p *ArticlesProcessor) SetLimit(limit int) {
+	p.limit = limit
+	p.storage.SetLimit(limit)
+}
+
+// SetWorkers sets the number of page fetchers, 0 means 2 per CPU,
+// must be called before TopArticles.
+func (p *ArticlesProcessor) SetWorkers(n int) {
+	p.workers = n
+}
+
+// TopArticles runs the crawl, the processor can be reused for the next run
+// but runs must not overlap.
+func (p *ArticlesProcessor) TopArticles(ctx context.Context) ([]storage.Article, error) {
+	p.progress.running.Store(true)
+	defer p.progress.running.Store(false)
+	p.reset()
+	if err := p.restore(); err != nil {
 		return nil, err
 	}
+	p.loadIncremental()
+
+	parent, work := ctx, ctx
+	if p.partial {
+		var cancel context.CancelFunc
+		work, cancel = p.drainContext(parent)
+		defer cancel()
+	}
+	g, ctx := errgroup.WithContext(work)
+	// pages are requested until the interrupt, in-flight ones are drained then(partial results)
+	dispatch, stopDispatch := context.WithCancel(ctx)
+	defer stopDispatch()
+	stop := context.AfterFunc(parent, stopDispatch)
+	defer stop()
 
-	if err := g.Wait(); err != nil {
+	err := p.runPipeline(ctx, dispatch, g)
+	if err == nil {
+		err = g.Wait()
+	}
+	finished := err == nil && parent.Err() == nil
+	p.finishCheckpoint(finished)
+	partial := p.partialResult(parent, work, err)
+	if err != nil && !partial {
 		return nil, err
 	}
+	if finished {
+		p.saveIncremental()
+	}
 
-	return p.storage.TopArticlesNames(), nil
+	_, span := tracer.Start(parent, "sort")
+	top := p.storage.TopArticles()
+	span.SetAttributes(attribute.Int("articles", len(top)), attribute.Bool("partial", partial))
+	span.End()
+
+	if partial {
+		return top, p.partialError(parent)
+	}
+	return top, nil
 }
 
-func (p *ArticlesProcessor) runPipeline(ctx context.Context, g *errgroup.Group) error {
-	firstPage, err := p.articlesAPI.FetchPage(ctx, 1)
+// reset prepares the per run state.
+func (p *ArticlesProcessor) reset() {
+	// small buffer to avoid potential blocking
+	// "Rely on metrics, not guesses." - see articles_channel_depth
+	p.out = make(OutChan, articlesapi.MaxRPSPerCurrentHost)
+	p.in = make(InChan, articlesapi.MaxRPSPerCurrentHost)
+	p.seen = nil
+	p.skip, p.completed = make(map[int]struct{}), nil
+	p.totalPages, p.total, p.perPage = 0, 0, 0
+	p.prevState, p.pages = nil, nil
+	p.failed = nil
+	p.checkpointAt = time.Now()
+	p.progress.reset()
+	p.storage.Reset()
+}
+
+// runPipeline starts the pipeline, dispatch stops requesting new pages.
+func (p *ArticlesProcessor) runPipeline(ctx, dispatch context.Context, g *errgroup.Group) error {
+	discoverCtx, span := tracer.Start(ctx, "discover")
+	p.progress.inFlight.Add(1)
+	firstPage, err := p.articlesAPI.FetchPage(discoverCtx, 1)
+	p.progress.inFlight.Add(-1)
+	if err == nil && firstPage == nil {
+		err = errors.New("no api data found")
+	}
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "discover failed")
+		span.End()
 		return err
 	}
-	if firstPage == nil {
-		return errors.New("no api data found")
+	span.SetAttributes(attribute.Int("total_pages", firstPage.TotalPages), attribute.Int("total", firstPage.Total))
+	span.End()
+	if err = p.checkDrift(firstPage.TotalPages, firstPage.Total); err != nil {
+		return err
+	}
+	p.totalPages, p.total, p.perPage = firstPage.TotalPages, firstPage.Total, firstPage.PerPage
+	p.progress.total.Store(int64(firstPage.TotalPages))
+	p.rec.SetTotals(firstPage.TotalPages, firstPage.Total)
+	if err = p.planIncremental(ctx, firstPage); err != nil {
+		return err
+	}
+	if _, ok := p.skip[1]; !ok {
+		p.out <- Page{Ctx: discoverCtx, Number: 1, Articles: firstPage.Data}
 	}
-	p.out <- firstPage.Data
 
 	p.runArticlesConsumer(ctx, g)
-	p.runArticlesFetcherPool(ctx, g)
-	p.sendPagesToProcess(firstPage.TotalPages, g)
+	p.runArticlesFetcherPool(ctx, dispatch, g)
+	p.sendPagesToProcess(dispatch, firstPage.TotalPages, g)
 
 	return nil
 }
 
 func (p *ArticlesProcessor) runArticlesConsumer(ctx context.Context, g *errgroup.Group) {
-	p.logger.Info("starting ArticlesConsumer worker gracefully stopped")
+	p.logger.Info("starting ArticlesConsumer worker")
 
 	g.Go(func() error {
 		defer func() {
@@ -83,30 +296,58 @@ func (p *ArticlesProcessor) runArticlesConsumer(ctx context.Context, g *errgroup
 			select {
 			case <-ctx.Done():
 				return nil
-			case articles, ok := <-p.out:
+			case page, ok := <-p.out:
 				if !ok {
 					return nil
 				}
+				p.metrics.ChannelDepth(metrics.ChannelOut, len(p.out))
 
-				for _, a := range articles {
-					if err := p.processArticle(a); err != nil {
-						return err
-					}
+				if err := p.consume(ctx, page); err != nil {
+					return err
 				}
+				p.progress.done.Add(1)
+				p.pageCompleted(page.Number)
+				p.keepPage(page.Number, p.total, page.Articles)
 			}
 		}
 	})
 }
 
-func (p *ArticlesProcessor) runArticlesFetcherPool(ctx context.Context, g *errgroup.Group) {
+// consume processes the batch of the page articles.
+func (p *ArticlesProcessor) consume(ctx context.Context, page Page) error {
+	if page.Ctx != nil {
+		ctx = page.Ctx
+	}
+	_, span := tracer.Start(ctx, "consume", trace.WithAttributes(
+		attribute.Int("page", page.Number),
+		attribute.Int("rows", len(page.Articles)),
+	))
+	defer span.End()
+
+	for _, a := range page.Articles {
+		if err := p.processArticle(a, page.Number); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "consume failed")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *ArticlesProcessor) runArticlesFetcherPool(ctx, dispatch context.Context, g *errgroup.Group) {
 	p.logger.Info("starting ArticlesFetcher pool")
 
 	g.Go(func() error {
 		subG, subCtx := errgroup.WithContext(ctx)
-		// "Rely on metrics, not guesses."
-		for i := 0; i < runtime.NumCPU()*2; i++ {
+		// "Rely on metrics, not guesses." - see articles_api_pages_in_flight and articles_api_limiter_wait_seconds
+		workers := p.workers
+		if workers <= 0 {
+			workers = runtime.NumCPU() * 2
+		}
+		for i := 0; i < workers; i++ {
 			subG.Go(func() error {
-				if err := p.producer(subCtx); err != nil {
+				if err := p.producer(subCtx, dispatch); err != nil {
 					return err
 				}
 				return nil
@@ -122,30 +363,58 @@ func (p *ArticlesProcessor) runArticlesFetcherPool(ctx context.Context, g *errgr
 	})
 }
 
-func (p *ArticlesProcessor) producer(ctx context.Context) error {
+func (p *ArticlesProcessor) producer(ctx, dispatch context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case page, ok := <-p.in:
-			if !ok {
+		case <-dispatch.Done():
+			return nil
+		case req, ok := <-p.in:
+			if !ok || dispatch.Err() != nil {
 				return nil
 			}
+			p.metrics.ChannelDepth(metrics.ChannelIn, len(p.in))
 
-			resp, err := p.articlesAPI.FetchPage(ctx, page)
-			if err != nil {
+			if stop, err := p.produce(ctx, req); stop || err != nil {
 				return err
 			}
-			if resp == nil {
-				break
-			}
+		}
+	}
+}
 
-			p.out <- resp.Data
+// produce fetches the requested page and sends it to the consumer,
+// stop is true when the pool is canceled.
+func (p *ArticlesProcessor) produce(ctx context.Context, req PageRequest) (stop bool, err error) {
+	// cancellation of the pool with the span of the request
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(req.Ctx))
+	ctx, span := tracer.Start(ctx, "page", trace.WithAttributes(attribute.Int("page", req.Number)))
+	defer span.End()
+
+	p.progress.inFlight.Add(1)
+	resp, err := p.articlesAPI.FetchPage(ctx, req.Number)
+	p.progress.inFlight.Add(-1)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.pageFailed(req.Number)
 		}
+		span.SetStatus(codes.Error, "page failed")
+		return true, err
+	}
+	if resp == nil {
+		return false, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return true, nil
+	case p.out <- Page{Ctx: ctx, Number: req.Number, Articles: resp.Data}:
 	}
+
+	return false, nil
 }
 
-func (p *ArticlesProcessor) sendPagesToProcess(pages int, g *errgroup.Group) {
+func (p *ArticlesProcessor) sendPagesToProcess(ctx context.Context, pages int, g *errgroup.Group) {
 	g.Go(func() error {
 		defer close(p.in)
 
@@ -154,14 +423,25 @@ func (p *ArticlesProcessor) sendPagesToProcess(pages int, g *errgroup.Group) {
 			if pages == 1 {
 				break
 			}
-			p.in <- pages
+			// completed before the resumed crawl was interrupted
+			if _, ok := p.skip[pages]; !ok {
+				// producers are gone on cancel, nobody reads the rest
+				select {
+				case <-ctx.Done():
+					return nil
+				case p.in <- PageRequest{Ctx: ctx, Number: pages}:
+				}
+			}
 			pages--
 		}
 		return nil
 	})
 }
 
-func (p *ArticlesProcessor) processArticle(article *articlesapi.Article) error {
+// processArticle processes the row of the page, called by the consumer only.
+func (p *ArticlesProcessor) processArticle(article *articlesapi.Article, page int) error {
+	p.rec.RowSeen()
+	p.metrics.RowProcessed()
 	a := storage.Article{}
 
 	if article.Title != nil {
@@ -169,15 +449,64 @@ func (p *ArticlesProcessor) processArticle(article *articlesapi.Article) error {
 	} else if article.Title == nil && article.StoryTitle != nil {
 		a.Name = *article.StoryTitle
 	} else if article.Title == nil && article.StoryTitle == nil {
+		p.rowDropped(report.DropNilTitle)
 		return nil
 	}
 
 	if article.NumComments == nil {
+		p.rowDropped(report.DropNilNumComments)
 		return nil
 	}
 	a.NumComments = uint64(*article.NumComments)
 
+	a.Author = article.Author
+	a.URL = article.URL
+	if a.URL == "" && article.StoryURL != nil {
+		a.URL = *article.StoryURL
+	}
+	if article.CreatedAt != nil {
+		a.CreatedAt = time.Unix(int64(*article.CreatedAt), 0).UTC()
+	}
+
+	if !p.filter.Accept(a) {
+		p.rowDropped(report.DropFiltered)
+		return nil
+	}
+
+	// one consumer, so no need to sync
+	key := keyOf(a)
+	if kept, ok := p.seen[key]; ok {
+		p.rowDropped(report.DropDeduped)
+		// pages arrive in any order: the duplicate with more comments wins, then the one of the lower page
+		if a.NumComments < kept.Article.NumComments || a.NumComments == kept.Article.NumComments && page >= kept.Page {
+			return nil
+		}
+		p.seen[key] = seenRow{Article: a, Page: page}
+		p.storage.Replace(kept.Article, a)
+		for _, c := range p.collectors {
+			c.Replace(kept.Article, a)
+		}
+		return nil
+	}
+	if p.seen == nil {
+		p.seen = make(map[articleKey]seenRow)
+	}
+	p.seen[key] = seenRow{Article: a, Page: page}
+
 	p.storage.Insert(a)
+	for _, c := range p.collectors {
+		c.Insert(a)
+	}
 
 	return nil
 }
+
+func keyOf(a storage.Article) articleKey {
+	return articleKey{Name: a.Name, Author: a.Author, CreatedAt: a.CreatedAt.Unix()}
+}
+
+// rowDropped records the dropped row in the run report and metrics.
+func (p *ArticlesProcessor) rowDropped(reason report.DropReason) {
+	p.rec.RowDropped(reason)
+	p.metrics.RowDropped(string(reason))
+}
@@ -0,0 +1,45 @@
+package articlesprocessor
+
+import (
+	"sync/atomic"
+
+	"articles-service/internal/storage"
+)
+
+type (
+	// State is the live progress of the crawl, e.g. for state dumps.
+	State struct {
+		Running    bool
+		TotalPages int
+		// processed pages including reused and resumed ones
+		PagesDone int
+		// page requests in progress
+		InFlight      int
+		LimiterTokens float64
+		// sorted copy of the top collected so far
+		Top []storage.Article
+	}
+	// progress is updated by the pipeline and read by State concurrently.
+	progress struct {
+		running               atomic.Bool
+		total, done, inFlight atomic.Int64
+	}
+)
+
+// State returns the progress of the current(or the last) crawl, safe to call during the crawl.
+func (p *ArticlesProcessor) State() State {
+	return State{
+		Running:       p.progress.running.Load(),
+		TotalPages:    int(p.progress.total.Load()),
+		PagesDone:     int(p.progress.done.Load()),
+		InFlight:      int(p.progress.inFlight.Load()),
+		LimiterTokens: p.articlesAPI.Tokens(),
+		Top:           p.storage.Snapshot(),
+	}
+}
+
+func (pr *progress) reset() {
+	pr.total.Store(0)
+	pr.done.Store(0)
+	pr.inFlight.Store(0)
+}
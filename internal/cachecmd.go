@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"articles-service/internal/articlesprocessor"
+)
+
+// cacheEntry describes the crawl state file.
+type cacheEntry struct {
+	// incremental or checkpoint
+	Kind      string    `json:"kind"`
+	Path      string    `json:"path"`
+	Exists    bool      `json:"exists"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	// kept pages of the incremental state, completed pages of the checkpoint
+	Pages      int `json:"pages"`
+	TotalPages int `json:"total_pages"`
+	Total      int `json:"total"`
+	// checkpoint only
+	Failed int `json:"failed,omitempty"`
+}
+
+// cacheFlags are the flags of the cache commands.
+type cacheFlags struct {
+	incremental string
+	checkpoint  string
+	format      string
+}
+
+// newCacheFlagSet defines the flags of the cache command, the completion walks them too.
+func newCacheFlagSet(cmd string, f *cacheFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("cache "+cmd, flag.ContinueOnError)
+	fs.StringVar(&f.incremental, "incremental", "", "state file of the incremental crawl")
+	fs.StringVar(&f.checkpoint, "checkpoint", "", "checkpoint file of the crawl")
+
+	var summary string
+	switch cmd {
+	case "inspect":
+		fs.StringVar(&f.format, "format", "table", "output format: table|json")
+		summary = "Print size, age and pages of the state files, missing files are listed too"
+	case "clear":
+		summary = "Remove the state files, the next run crawls all pages"
+	}
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "usage: top-articles cache %s -incremental=FILE -checkpoint=FILE\n\n", cmd)
+		fmt.Fprintf(w, "%s.\n\n", summary)
+		fmt.Fprintln(w, "Flags:")
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// RunCache runs the cache command: inspect or clear the crawl state kept between runs,
+// the incremental state(-incremental) and the checkpoint(-checkpoint/-resume).
+//
+//	cache inspect|clear [flags]
+func RunCache(_ context.Context, argv []string) error {
+	usage := func(w io.Writer) {
+		fmt.Fprintln(w, "usage: top-articles cache inspect|clear [flags]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Commands:")
+		fmt.Fprintln(w, "  inspect  print size, age and pages of the state files")
+		fmt.Fprintln(w, "  clear    remove the state files, the next run crawls all pages")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, `Run "top-articles cache <command> -h" for the flags of the command.`)
+	}
+	if len(argv) == 0 {
+		usage(os.Stderr)
+		return invalidArgs(errors.New("cache expects a command"))
+	}
+
+	cmd, argv := argv[0], argv[1:]
+	switch cmd {
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+		return nil
+	}
+
+	var f cacheFlags
+	fs := newCacheFlagSet(cmd, &f)
+
+	switch cmd {
+	case "inspect":
+		if err := fs.Parse(argv); err != nil {
+			return err
+		}
+		if f.incremental == "" && f.checkpoint == "" {
+			return invalidArgs(errors.New("inspect expects -incremental and/or -checkpoint"))
+		}
+		var entries []cacheEntry
+		if f.incremental != "" {
+			e, err := inspectIncremental(f.incremental)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		if f.checkpoint != "" {
+			e, err := inspectCheckpoint(f.checkpoint)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return listCache(os.Stdout, entries, f.format)
+
+	case "clear":
+		if err := fs.Parse(argv); err != nil {
+			return err
+		}
+		if f.incremental == "" && f.checkpoint == "" {
+			return invalidArgs(errors.New("clear expects -incremental and/or -checkpoint"))
+		}
+		for _, path := range []string{f.incremental, f.checkpoint} {
+			if path == "" {
+				continue
+			}
+			err := os.Remove(path)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+			case err != nil:
+				return err
+			default:
+				fmt.Fprintf(os.Stdout, "removed %s\n", path)
+			}
+		}
+		return nil
+
+	default:
+		usage(os.Stderr)
+		return invalidArgs(fmt.Errorf("unknown cache command %q", cmd))
+	}
+}
+
+func inspectIncremental(path string) (cacheEntry, error) {
+	e, ok, err := statCache("incremental", path)
+	if !ok || err != nil {
+		return e, err
+	}
+	state, err := articlesprocessor.LoadIncrementalState(path)
+	if err != nil {
+		return e, err
+	}
+	e.UpdatedAt = state.UpdatedAt
+	e.Pages, e.TotalPages, e.Total = len(state.Pages), state.TotalPages, state.Total
+	return e, nil
+}
+
+func inspectCheckpoint(path string) (cacheEntry, error) {
+	e, ok, err := statCache("checkpoint", path)
+	if !ok || err != nil {
+		return e, err
+	}
+	cp, err := articlesprocessor.LoadCheckpoint(path)
+	if err != nil {
+		return e, err
+	}
+	e.UpdatedAt = cp.UpdatedAt
+	e.Pages, e.TotalPages, e.Total = len(cp.Completed), cp.TotalPages, cp.Total
+	e.Failed = len(cp.Failed)
+	return e, nil
+}
+
+// statCache fills the entry by the file info, ok is false when the file doesn't exist.
+func statCache(kind, path string) (e cacheEntry, ok bool, err error) {
+	e = cacheEntry{Kind: kind, Path: path}
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return e, false, nil
+	}
+	if err != nil {
+		return e, false, err
+	}
+	e.Exists, e.Size = true, fi.Size()
+	return e, true, nil
+}
+
+func listCache(w io.Writer, entries []cacheEntry, format string) error {
+	switch format {
+	case "json":
+		return writeIndentedJSON(w, entries)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KIND\tPATH\tSIZE\tUPDATED\tPAGES\tTOTAL")
+		for _, e := range entries {
+			if !e.Exists {
+				fmt.Fprintf(tw, "%s\t%s\t-\tmissing\t-\t-\n", e.Kind, e.Path)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d/%d\t%d\n",
+				e.Kind,
+				e.Path,
+				e.Size,
+				e.UpdatedAt.Format(time.DateTime),
+				e.Pages, e.TotalPages,
+				e.Total,
+			)
+		}
+		return tw.Flush()
+	default:
+		return invalidArgs(fmt.Errorf("unknown inspect format %q, expected one of: table, json", format))
+	}
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+)
+
+// pipeline commands, they share the flags and the config
+const (
+	cmdTop     = "top"
+	cmdAuthors = "authors"
+	cmdStories = "stories"
+	cmdDomains = "domains"
+	cmdStats   = "stats"
+	cmdExport  = "export"
+	cmdServe   = "serve"
+)
+
+var pipelineCommands = []string{cmdTop, cmdAuthors, cmdStories, cmdDomains, cmdStats, cmdExport, cmdServe}
+
+// Version of the binary, set by the build:
+//
+//	go build -ldflags "-X articles-service/internal.Version=v1.2.3"
+var Version = "dev"
+
+// command of the binary.
+type command struct {
+	name    string
+	summary string
+	// nested commands, e.g. cache inspect|clear
+	subcommands []string
+	// returns the exit code
+	run func(ctx context.Context, name string, argv []string) int
+	// flags of the command or its subcommand for the completion, nil for pipeline commands(see newFlagSet)
+	flags func(sub string) *flag.FlagSet
+}
+
+var commands []command
+
+func init() {
+	// the completion command refers to the table
+	commands = []command{
+		{name: cmdTop, summary: "top of the most commented articles", run: runPipeline},
+		{name: cmdAuthors, summary: "authors leaderboard by comments", run: runPipeline},
+		{name: cmdStories, summary: "stories leaderboard: comments of the articles with the same title", run: runPipeline},
+		{name: cmdDomains, summary: "domain leaderboard derived from article urls", run: runPipeline},
+		{name: cmdStats, summary: "crawl and write the JSON run report only", run: runPipeline},
+		{name: cmdExport, summary: "crawl and write the whole run(top, authors, stories, domains, report) as JSON", run: runPipeline},
+		{
+			name: "diff", summary: "compare two top lists or a saved one with the current run",
+			run: runFunc(RunDiff), flags: func(string) *flag.FlagSet { return newDiffFlagSet(&diffFlags{}) },
+		},
+		{name: cmdServe, summary: "run crawls on schedule and serve the results over HTTP", run: runPipeline},
+		{
+			name: "cache", summary: "inspect or clear the crawl state kept between runs(incremental state, checkpoint)",
+			subcommands: []string{"inspect", "clear"},
+			run:         runFunc(RunCache), flags: func(sub string) *flag.FlagSet { return newCacheFlagSet(sub, &cacheFlags{}) },
+		},
+		{
+			name: "snapshots", summary: "list, show and prune the stored runs",
+			subcommands: []string{"list", "show", "prune"},
+			run:         runFunc(RunSnapshots), flags: func(sub string) *flag.FlagSet { return newSnapshotsFlagSet(sub, &snapshotsFlags{}) },
+		},
+		{
+			name: "version", summary: "print the version",
+			run: runFunc(RunVersion), flags: func(string) *flag.FlagSet { return newVersionFlagSet(new(bool)) },
+		},
+		{
+			name: "completion", summary: "print the shell completion script: bash|zsh|fish",
+			subcommands: []string{"bash", "zsh", "fish"},
+			run:         runFunc(RunCompletion),
+		},
+	}
+}
+
+// Execute runs the command of the command line(without the program name) and returns the exit code.
+// The flags-only command line of the previous versions runs the top command.
+func Execute(ctx context.Context, argv []string) int {
+	if len(argv) == 0 {
+		printUsage(os.Stderr)
+		return ExitInvalidArgs
+	}
+	name := cmdTop
+	switch argv[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return ExitOK
+	}
+	if !strings.HasPrefix(argv[0], "-") {
+		name, argv = argv[0], argv[1:]
+	}
+
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return ExitInvalidArgs
+	}
+	return cmd.run(ctx, name, argv)
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: top-articles <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	_ = tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "top-articles <command> -h" for the flags of the command.`)
+}
+
+// commandUsage is the help of the pipeline command.
+func commandUsage(fs *flag.FlagSet, name string) {
+	w := fs.Output()
+	cmd, _ := lookupCommand(name)
+	fmt.Fprintf(w, "usage: top-articles %s [flags]\n\n", name)
+	fmt.Fprintf(w, "%s.\n", strings.ToUpper(cmd.summary[:1])+cmd.summary[1:])
+	fmt.Fprintln(w, "Settings of other commands in the config file are skipped, so one config file serves them all.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs.PrintDefaults()
+}
+
+// runPipeline runs the app of the pipeline command.
+func runPipeline(ctx context.Context, name string, argv []string) int {
+	app, err := newCommandApp(name, argv)
+	if errors.Is(err, flag.ErrHelp) || errors.Is(err, ErrConfigPrinted) {
+		return ExitOK
+	}
+	if err != nil {
+		return Fail(argv, fmt.Errorf("init articles service failed: %w", err))
+	}
+
+	code := ExitOK
+	if err = app.Run(ctx); err != nil {
+		code = app.Fail(err)
+	}
+	app.Close()
+	return code
+}
+
+// runFunc adapts the command returning an error.
+func runFunc(run func(ctx context.Context, argv []string) error) func(ctx context.Context, name string, argv []string) int {
+	return func(ctx context.Context, _ string, argv []string) int {
+		err := run(ctx, argv)
+		if err == nil || errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		log.Print(err)
+		return NewFailure(err).ExitCode
+	}
+}
+
+// newVersionFlagSet defines the flags of the version command.
+func newVersionFlagSet(short *bool) *flag.FlagSet {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	fs.BoolVar(short, "short", false, "print the version only")
+	return fs
+}
+
+// RunVersion runs the version command.
+//
+//	version [-short]
+func RunVersion(_ context.Context, argv []string) error {
+	var short bool
+	fs := newVersionFlagSet(&short)
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+	if short {
+		_, err := fmt.Println(Version)
+		return err
+	}
+
+	revision, modified := "unknown", false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	_, err := fmt.Printf("top-articles %s (revision %s, %s %s/%s)\n", Version, revision, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return err
+}
+
+// writeIndentedJSON writes the value as indented JSON.
+func writeIndentedJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
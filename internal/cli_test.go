@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/output"
+)
+
+func TestExecute(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, ExitOK, Execute(ctx, []string{"help"}))
+	assert.Equal(t, ExitInvalidArgs, Execute(ctx, []string{"nope"}))
+	assert.Equal(t, ExitOK, Execute(ctx, []string{"top", "-h"}))
+	assert.Equal(t, ExitInvalidArgs, Execute(ctx, []string{"stats", "-l=5", "-format=csv"}))
+	// flags only command line runs the top command
+	assert.Equal(t, ExitInvalidArgs, Execute(ctx, []string{"-l=500"}))
+}
+
+func TestParseCommandArgs(t *testing.T) {
+	a, err := parseCommandArgs(cmdDomains, []string{"-l=5"})
+	require.NoError(t, err)
+	assert.True(t, a.byDomain)
+	assert.Equal(t, cmdDomains, a.command)
+
+	a, err = parseCommandArgs(cmdServe, nil)
+	require.NoError(t, err)
+	assert.True(t, a.serve)
+	assert.Equal(t, MaxLimit, a.limit)
+
+	// flags of other commands are not defined
+	_, err = parseCommandArgs(cmdAuthors, []string{"-l=5", "-windows=tumbling"})
+	assert.ErrorContains(t, err, "not defined: -windows")
+	_, err = parseCommandArgs(cmdStories, []string{"-l=5", "-serve"})
+	assert.ErrorContains(t, err, "not defined: -serve")
+	_, err = parseCommandArgs(cmdStats, []string{"-l=5", "-template=x"})
+	assert.ErrorContains(t, err, "not defined: -template")
+
+	// but their settings in the shared config are skipped
+	path := writeConfig(t, "config.yaml", "limit: 5\nformat: json\nwindows: tumbling\naddr: :9000\n")
+	t.Setenv("ARTICLES_SERVE", "true")
+	a, err = parseCommandArgs(cmdStats, []string{"-config=" + path})
+	require.NoError(t, err)
+	assert.Equal(t, 5, a.limit)
+	assert.False(t, a.serve)
+	assert.Nil(t, a.windows)
+	assert.Empty(t, a.ignoredEnv)
+	a, err = parseCommandArgs(cmdTop, []string{"-config=" + path})
+	require.NoError(t, err)
+	assert.Equal(t, output.JSON, a.format)
+	assert.True(t, a.serve)
+}
+
+func TestCommandFlags(t *testing.T) {
+	subFlags := func(cmd, sub string) []string {
+		c, ok := lookupCommand(cmd)
+		require.True(t, ok, cmd)
+		var names []string
+		for _, f := range commandFlags(c, sub) {
+			names = append(names, f.name)
+		}
+		return names
+	}
+	flags := func(cmd string) []string { return subFlags(cmd, "") }
+
+	for _, cmd := range pipelineCommands {
+		assert.Subset(t, flags(cmd), []string{"l", "config", "rps", "log-level", "author"}, cmd)
+	}
+	assert.Subset(t, flags(cmdTop), []string{"serve", "domains", "windows", "format", "addr", "watch"})
+	assert.NotContains(t, flags(cmdServe), "watch")
+	assert.NotContains(t, flags(cmdServe), "format")
+	for _, cmd := range []string{cmdAuthors, cmdStories, cmdDomains, cmdStats, cmdExport} {
+		assert.NotContains(t, flags(cmd), "serve", cmd)
+		assert.NotContains(t, flags(cmd), "domains", cmd)
+	}
+	for _, cmd := range []string{cmdStats, cmdExport} {
+		for _, name := range []string{"format", "template", "feed-title"} {
+			assert.NotContains(t, flags(cmd), name, cmd)
+		}
+	}
+	assert.NotContains(t, flags(cmdAuthors), "windows")
+	assert.Contains(t, flags(cmdExport), "windows")
+
+	// the flags of the commands' own flag sets
+	assert.Contains(t, flags("diff"), "config")
+	assert.Equal(t, []string{"short"}, flags("version"))
+	assert.Equal(t, []string{"checkpoint", "format", "incremental"}, subFlags("cache", "inspect"))
+	assert.Equal(t, []string{"checkpoint", "incremental"}, subFlags("cache", "clear"))
+	assert.Equal(t, []string{"format", "n", "snapshot-dir"}, subFlags("snapshots", "list"))
+	assert.Equal(t, []string{"format", "snapshot-dir"}, subFlags("snapshots", "show"))
+	assert.Equal(t, []string{"keep", "older-than", "snapshot-dir"}, subFlags("snapshots", "prune"))
+	assert.Empty(t, subFlags("completion", "bash"))
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		require.NoError(t, writeCompletion(&buf, shell), shell)
+		script := buf.String()
+		for _, word := range []string{"stories", "inspect", "max-churn", "drain-timeout"} {
+			assert.Contains(t, script, word, shell)
+		}
+
+		// syntax check when the shell is installed
+		if _, err := exec.LookPath(shell); err != nil || shell == "fish" {
+			continue
+		}
+		path := filepath.Join(t.TempDir(), "completion."+shell)
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+		out, err := exec.Command(shell, "-n", path).CombinedOutput()
+		assert.NoError(t, err, "%s: %s", shell, out)
+	}
+	assert.Error(t, writeCompletion(&bytes.Buffer{}, "tcsh"))
+}
+
+func TestRunCache(t *testing.T) {
+	dir := t.TempDir()
+	incremental, checkpoint := filepath.Join(dir, "state.json"), filepath.Join(dir, "crawl.ckpt")
+	require.NoError(t, os.WriteFile(incremental, []byte(`{"version":1,"total":4,"total_pages":2,"per_page":2,"pages":{"1":{"hash":"h"}}}`), 0o644))
+
+	e, err := inspectIncremental(incremental)
+	require.NoError(t, err)
+	assert.Equal(t, cacheEntry{Kind: "incremental", Path: incremental, Exists: true, Size: e.Size, Pages: 1, TotalPages: 2, Total: 4}, e)
+	e, err = inspectCheckpoint(checkpoint)
+	require.NoError(t, err)
+	assert.False(t, e.Exists)
+
+	require.NoError(t, RunCache(context.Background(), []string{"clear", "-incremental=" + incremental, "-checkpoint=" + checkpoint}))
+	_, err = os.Stat(incremental)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.ErrorIs(t, RunCache(context.Background(), []string{"inspect"}), ErrInvalidArgs)
+}
+
+func TestExecute_CacheHelp(t *testing.T) {
+	ctx := context.Background()
+	for _, argv := range [][]string{{"cache", "-h"}, {"cache", "help"}, {"cache", "inspect", "-h"}, {"cache", "clear", "-h"}, {"snapshots", "-h"}} {
+		assert.Equal(t, ExitOK, Execute(ctx, argv), argv)
+	}
+	assert.Equal(t, ExitInvalidArgs, Execute(ctx, []string{"cache"}))
+	assert.Equal(t, ExitInvalidArgs, Execute(ctx, []string{"cache", "nope"}))
+}
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RunCompletion runs the completion command: prints the completion script of the shell.
+//
+//	completion bash|zsh|fish
+//
+// e.g. source <(top-articles completion bash)
+func RunCompletion(_ context.Context, argv []string) error {
+	fs := flag.NewFlagSet("completion", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: top-articles completion bash|zsh|fish")
+	}
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return invalidArgs(errors.New("completion expects the shell"))
+	}
+	return writeCompletion(os.Stdout, fs.Arg(0))
+}
+
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return writeBashCompletion(w)
+	case "zsh":
+		return writeZshCompletion(w)
+	case "fish":
+		return writeFishCompletion(w)
+	default:
+		return invalidArgs(fmt.Errorf("unknown shell %q, expected one of: bash, zsh, fish", shell))
+	}
+}
+
+// completionFlag is the flag of the command offered by the completion.
+type completionFlag struct {
+	name  string
+	usage string
+}
+
+// commandFlags returns flags of the command or its subcommand, pipeline commands share them.
+func commandFlags(cmd command, sub string) []completionFlag {
+	var fs *flag.FlagSet
+	switch {
+	case cmd.flags != nil:
+		fs = cmd.flags(sub)
+	case cmd.subcommands == nil:
+		fs = newFlagSet(cmd.name, &args{}, &rawFlags{})
+	default:
+		return nil
+	}
+
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, completionFlag{name: f.Name, usage: f.Usage})
+	})
+	return flags
+}
+
+// flagWords returns the flags of the command or its subcommand as completion words.
+func flagWords(cmd command, sub string) string {
+	var words []string
+	for _, f := range commandFlags(cmd, sub) {
+		words = append(words, "-"+f.name)
+	}
+	return strings.Join(words, " ")
+}
+
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+func writeBashCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# bash completion of top-articles, source <(top-articles completion bash)\n")
+	b.WriteString("_top_articles() {\n")
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" words=\"\"\n")
+	b.WriteString("\tif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	b.WriteString("\t\treturn\n\tfi\n")
+	b.WriteString("\tcase \"${COMP_WORDS[1]}\" in\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\t%s)\n", cmd.name)
+		if len(cmd.subcommands) == 0 {
+			fmt.Fprintf(&b, "\t\twords=%q\n\t\t;;\n", flagWords(cmd, ""))
+			continue
+		}
+		b.WriteString("\t\tif [[ $COMP_CWORD -eq 2 ]]; then\n")
+		fmt.Fprintf(&b, "\t\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(cmd.subcommands, " "))
+		b.WriteString("\t\t\treturn\n\t\tfi\n")
+		b.WriteString("\t\tcase \"${COMP_WORDS[2]}\" in\n")
+		for _, sub := range cmd.subcommands {
+			fmt.Fprintf(&b, "\t\t%s)\n\t\t\twords=%q\n\t\t\t;;\n", sub, flagWords(cmd, sub))
+		}
+		b.WriteString("\t\tesac\n\t\t;;\n")
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o default -F _top_articles top-articles\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZshCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("#compdef top-articles\n")
+	b.WriteString("# zsh completion of top-articles, source <(top-articles completion zsh)\n")
+	b.WriteString("_top_articles() {\n")
+	b.WriteString("\tlocal -a commands\n\tcommands=(\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\t\t%s\n", zshQuote(cmd.name+":"+cmd.summary))
+	}
+	b.WriteString("\t)\n")
+	b.WriteString("\tif (( CURRENT == 2 )); then\n\t\t_describe 'command' commands\n\t\treturn\n\tfi\n")
+	b.WriteString("\tcase $words[2] in\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\t%s)\n", cmd.name)
+		if len(cmd.subcommands) == 0 {
+			writeZshArguments(&b, "\t\t", commandFlags(cmd, ""))
+			continue
+		}
+		fmt.Fprintf(&b, "\t\tif (( CURRENT == 3 )); then\n\t\t\tcompadd -- %s\n\t\t\treturn\n\t\tfi\n", strings.Join(cmd.subcommands, " "))
+		b.WriteString("\t\tcase $words[3] in\n")
+		for _, sub := range cmd.subcommands {
+			fmt.Fprintf(&b, "\t\t%s)\n", sub)
+			writeZshArguments(&b, "\t\t\t", commandFlags(cmd, sub))
+		}
+		b.WriteString("\t\tesac\n\t\t;;\n")
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("}\n")
+	b.WriteString("compdef _top_articles top-articles\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeZshArguments writes the _arguments case of the flags.
+func writeZshArguments(b *strings.Builder, indent string, flags []completionFlag) {
+	b.WriteString(indent + "_arguments")
+	for _, f := range flags {
+		spec := "-" + f.name
+		if f.usage != "" {
+			spec += "[" + zshEscape(f.usage) + "]"
+		}
+		fmt.Fprintf(b, " \\\n%s\t%s", indent, zshQuote(spec))
+	}
+	fmt.Fprintf(b, " \\\n%s\t'*:file:_files'\n%s;;\n", indent, indent)
+}
+
+func writeFishCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# fish completion of top-articles, top-articles completion fish | source\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "complete -c top-articles -n __fish_use_subcommand -f -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+	for _, cmd := range commands {
+		cond := fishQuote("__fish_seen_subcommand_from " + cmd.name)
+		if len(cmd.subcommands) == 0 {
+			writeFishFlags(&b, cond, commandFlags(cmd, ""))
+			continue
+		}
+		fmt.Fprintf(&b, "complete -c top-articles -n %s -f -a %s\n", cond, fishQuote(strings.Join(cmd.subcommands, " ")))
+		for _, sub := range cmd.subcommands {
+			subCond := fishQuote("__fish_seen_subcommand_from " + cmd.name + "; and __fish_seen_subcommand_from " + sub)
+			writeFishFlags(&b, subCond, commandFlags(cmd, sub))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFishFlags writes the completions of the flags under the condition.
+func writeFishFlags(b *strings.Builder, cond string, flags []completionFlag) {
+	for _, f := range flags {
+		fmt.Fprintf(b, "complete -c top-articles -n %s -o %s", cond, f.name)
+		if f.usage != "" {
+			fmt.Fprintf(b, " -d %s", fishQuote(f.usage))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// zshQuote single-quotes the word for zsh.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape escapes the description of _arguments specs.
+func zshEscape(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// fishQuote single-quotes the word for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are layered: defaults < config file < ARTICLES_* env vars < flags.
+// Every flag is a setting with the same name(-l is "limit"), nested keys of the config file
+// are joined with "-", e.g. log: {level: debug} is -log-level, ARTICLES_LOG_LEVEL in env.
+
+const (
+	envPrefix = "ARTICLES_"
+	// path of the config file when -config is not set
+	envConfig = envPrefix + "CONFIG"
+)
+
+// ErrConfigPrinted is returned by NewApp after -print-config, nothing to run then.
+var ErrConfigPrinted = errors.New("config printed")
+
+// flags which are not settings
+var notSettings = map[string]bool{"config": true, "print-config": true}
+
+// settingKey is the name of the flag in config files and env vars.
+func settingKey(flagName string) string {
+	if flagName == "l" {
+		return "limit"
+	}
+	return flagName
+}
+
+// applySettings sets flags not set on the command line from the config file
+// and then from env vars. Unknown keys of the file are errors, unknown ARTICLES_* env vars
+// could belong to the deployment, they are returned to be logged.
+func applySettings(fs *flag.FlagSet, path string, environ []string) (ignored []string, err error) {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	byKey := make(map[string]*flag.Flag)
+	fs.VisitAll(func(f *flag.Flag) {
+		if !notSettings[f.Name] {
+			byKey[settingKey(f.Name)] = f
+		}
+	})
+
+	// settings of other commands are skipped, one config file serves them all
+	shared := pipelineSettings()
+	set := func(source, key, value string) error {
+		f, ok := byKey[key]
+		if !ok && shared[key] {
+			return nil
+		}
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", source, key)
+		}
+		if explicit[f.Name] {
+			return nil
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			return fmt.Errorf("%s: invalid value %q of %s: %w", source, value, key, err)
+		}
+		return nil
+	}
+
+	if path != "" {
+		settings, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(settings))
+		for k := range settings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err = set(path, k, settings[k]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(name, envPrefix)
+		if !ok || name == envConfig {
+			continue
+		}
+		key = strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		if !shared[key] {
+			ignored = append(ignored, name)
+			continue
+		}
+		if err := set(name, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return ignored, nil
+}
+
+// pipelineSettings returns keys of the settings of all pipeline commands.
+func pipelineSettings() map[string]bool {
+	keys := make(map[string]bool)
+	for _, cmd := range pipelineCommands {
+		newFlagSet(cmd, &args{}, &rawFlags{}).VisitAll(func(f *flag.Flag) {
+			if !notSettings[f.Name] {
+				keys[settingKey(f.Name)] = true
+			}
+		})
+	}
+	return keys
+}
+
+// readConfigFile reads the YAML(or JSON by the .json extension) config into flat settings.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var doc map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	settings := make(map[string]string)
+	if err = flatten(settings, "", doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// flatten joins nested keys with "-", lists are comma separated like in flags.
+func flatten(dst map[string]string, prefix string, doc map[string]any) error {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "-" + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flatten(dst, key, v); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, err := scalar(key, item)
+				if err != nil {
+					return err
+				}
+				items[i] = s
+			}
+			dst[key] = strings.Join(items, ",")
+		default:
+			s, err := scalar(key, v)
+			if err != nil {
+				return err
+			}
+			dst[key] = s
+		}
+	}
+	return nil
+}
+
+func scalar(key string, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		// unquoted dates of yaml
+		return v.Format(time.RFC3339), nil
+	case nil:
+		return "", fmt.Errorf("%s: empty value", key)
+	}
+	return "", fmt.Errorf("%s: unsupported value %v", key, v)
+}
+
+// effectiveConfig returns every setting with its typed value.
+func effectiveConfig(fs *flag.FlagSet) map[string]any {
+	cfg := make(map[string]any)
+	fs.VisitAll(func(f *flag.Flag) {
+		if notSettings[f.Name] {
+			return
+		}
+		var v any = f.Value.String()
+		if g, ok := f.Value.(flag.Getter); ok {
+			v = g.Get()
+		}
+		if d, ok := v.(time.Duration); ok {
+			v = d.String()
+		}
+		cfg[settingKey(f.Name)] = v
+	})
+	return cfg
+}
+
+// printConfig writes the effective config in the config file format.
+func printConfig(w io.Writer, cfg map[string]any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
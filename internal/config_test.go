@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	return path
+}
+
+func TestParseArgs_Layers(t *testing.T) {
+	yamlConfig := writeConfig(t, "config.yaml", `
+limit: 5
+rps: 2.5
+burst: 4
+log:
+  level: debug
+author: [alice, bob]
+created-after: 2024-01-01
+`)
+	jsonConfig := writeConfig(t, "config.json", `{"limit": 7, "api": {"url": "http://mirror"}, "workers": 3}`)
+
+	tests := []struct {
+		name    string
+		argv    []string
+		env     map[string]string
+		wantErr string
+		assert  func(t *testing.T, a args)
+	}{
+		{
+			name: "yaml file",
+			argv: []string{"-config=" + yamlConfig},
+			assert: func(t *testing.T, a args) {
+				assert.Equal(t, 5, a.limit)
+				assert.Equal(t, 2.5, a.api.RPS)
+				assert.Equal(t, "debug", a.log.Level)
+				assert.Equal(t, []string{"alice", "bob"}, a.filter.Authors)
+				assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), a.filter.CreatedAfter)
+			},
+		},
+		{
+			name: "json file from env",
+			env:  map[string]string{"ARTICLES_CONFIG": jsonConfig},
+			assert: func(t *testing.T, a args) {
+				assert.Equal(t, 7, a.limit)
+				assert.Equal(t, "http://mirror", a.api.BaseURL)
+				assert.Equal(t, 3, a.workers)
+			},
+		},
+		{
+			name: "env over file, flags over env",
+			argv: []string{"-config=" + yamlConfig, "-l=9"},
+			env:  map[string]string{"ARTICLES_LIMIT": "8", "ARTICLES_BURST": "6"},
+			assert: func(t *testing.T, a args) {
+				assert.Equal(t, 9, a.limit)
+				assert.Equal(t, 6, a.api.Burst)
+				assert.Equal(t, 2.5, a.api.RPS)
+				assert.Equal(t, "9", a.params["l"])
+				assert.Equal(t, "6", a.params["burst"])
+			},
+		},
+		{
+			name:    "unknown key",
+			argv:    []string{"-config=" + writeConfig(t, "typo.yaml", "limt: 5")},
+			wantErr: `unknown setting "limt"`,
+		},
+		{
+			name: "unknown env is ignored",
+			argv: []string{"-l=1"},
+			env:  map[string]string{"ARTICLES_NOPE": "1", "ARTICLES_RPS": "3"},
+			assert: func(t *testing.T, a args) {
+				assert.Equal(t, 3.0, a.api.RPS)
+				assert.Equal(t, []string{"ARTICLES_NOPE"}, a.ignoredEnv)
+			},
+		},
+		{
+			name:    "invalid value",
+			argv:    []string{"-config=" + writeConfig(t, "bad.yaml", "limit: 1\nrps: fast")},
+			wantErr: `invalid value "fast" of rps`,
+		},
+		{
+			name:    "limit above max-limit",
+			argv:    []string{"-l=20", "-max-limit=10"},
+			wantErr: "out of range: 10",
+		},
+		{
+			name:    "missing file",
+			argv:    []string{"-l=1", "-config=missing.yaml"},
+			wantErr: "read config",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			a, err := parseArgs(tt.argv)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.assert(t, a)
+		})
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	a, err := parseArgs([]string{"-l=3", "-rps=2", "-print-config"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, printConfig(&buf, a.settings))
+
+	var cfg map[string]any
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &cfg))
+	assert.Equal(t, 3, cfg["limit"])
+	assert.Equal(t, 2, cfg["rps"])
+	assert.Equal(t, "500ms", cfg["retry-delay"])
+	assert.NotContains(t, cfg, "l")
+	assert.NotContains(t, cfg, "print-config")
+
+	// the printed config is a valid config file
+	path := writeConfig(t, "printed.yaml", buf.String())
+	b, err := parseArgs([]string{"-config=" + path})
+	require.NoError(t, err)
+	assert.Equal(t, 3, b.limit)
+	assert.Equal(t, 2.0, b.api.RPS)
+}
@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"sort"
+	"strconv"
+
+	"articles-service/internal/output"
+)
+
+const (
+	Entered  ChangeType = "entered"
+	Left     ChangeType = "left"
+	Moved    ChangeType = "moved"
+	Comments ChangeType = "comments"
+)
+
+type (
+	ChangeType string
+	// Change of one article between two top lists.
+	Change struct {
+		Type          ChangeType `json:"type"`
+		Title         string     `json:"title"`
+		Author        string     `json:"author,omitempty"`
+		URL           string     `json:"url,omitempty"`
+		OldRank       int        `json:"old_rank,omitempty"`
+		NewRank       int        `json:"new_rank,omitempty"`
+		OldComments   uint64     `json:"old_comments"`
+		NewComments   uint64     `json:"new_comments"`
+		CommentsDelta int64      `json:"comments_delta"`
+	}
+	// Result lists changes ordered by type(entered, left, moved, comments) and rank.
+	Result struct {
+		Changes []Change `json:"changes"`
+		// size of the new top list
+		size int
+	}
+	// Summary counts changes by type, Churn is the share of the new top list
+	// that wasn't in the old one.
+	Summary struct {
+		Entered  int     `json:"entered"`
+		Left     int     `json:"left"`
+		Moved    int     `json:"moved"`
+		Comments int     `json:"comments"`
+		Churn    float64 `json:"churn"`
+	}
+)
+
+// Key identifies the article in the top list,
+// the same as dedup key of the processor: title, author and created_at.
+func Key(r output.Record) string {
+	key := r.Title + "\x00" + r.Author
+	if !r.CreatedAt.IsZero() {
+		key += "\x00" + strconv.FormatInt(r.CreatedAt.Unix(), 10)
+	}
+	return key
+}
+
+// Compare returns changes from the old to the new top list.
+func Compare(old, new []output.Record) Result {
+	oldByKey := make(map[string]output.Record, len(old))
+	for _, r := range old {
+		oldByKey[Key(r)] = r
+	}
+
+	changes := make([]Change, 0)
+	seen := make(map[string]struct{}, len(new))
+	for _, n := range new {
+		k := Key(n)
+		seen[k] = struct{}{}
+
+		o, ok := oldByKey[k]
+		if !ok {
+			c := change(Entered, n)
+			c.NewRank, c.NewComments = n.Rank, n.Comments
+			c.CommentsDelta = int64(n.Comments)
+			changes = append(changes, c)
+			continue
+		}
+
+		if o.Rank == n.Rank && o.Comments == n.Comments {
+			continue
+		}
+		typ := Moved
+		if o.Rank == n.Rank {
+			typ = Comments
+		}
+		c := change(typ, n)
+		c.OldRank, c.NewRank = o.Rank, n.Rank
+		c.OldComments, c.NewComments = o.Comments, n.Comments
+		c.CommentsDelta = int64(n.Comments) - int64(o.Comments)
+		changes = append(changes, c)
+	}
+
+	for _, o := range old {
+		if _, ok := seen[Key(o)]; ok {
+			continue
+		}
+		c := change(Left, o)
+		c.OldRank, c.OldComments = o.Rank, o.Comments
+		c.CommentsDelta = -int64(o.Comments)
+		changes = append(changes, c)
+	}
+
+	order := map[ChangeType]int{Entered: 0, Left: 1, Moved: 2, Comments: 3}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if order[changes[i].Type] != order[changes[j].Type] {
+			return order[changes[i].Type] < order[changes[j].Type]
+		}
+		return rank(changes[i]) < rank(changes[j])
+	})
+
+	return Result{Changes: changes, size: len(new)}
+}
+
+// Top returns records ranked up to n, all of them when n <= 0.
+func Top(records []output.Record, n int) []output.Record {
+	if n <= 0 {
+		return records
+	}
+	res := make([]output.Record, 0, n)
+	for _, r := range records {
+		if r.Rank <= n {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func (r Result) Empty() bool { return len(r.Changes) == 0 }
+
+// Count returns the number of changes of the type.
+func (r Result) Count(t ChangeType) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary returns the counts of changes and the churn.
+func (r Result) Summary() Summary {
+	s := Summary{
+		Entered:  r.Count(Entered),
+		Left:     r.Count(Left),
+		Moved:    r.Count(Moved),
+		Comments: r.Count(Comments),
+	}
+	if r.size != 0 {
+		s.Churn = float64(s.Entered) / float64(r.size)
+	}
+	return s
+}
+
+func change(t ChangeType, r output.Record) Change {
+	return Change{Type: t, Title: r.Title, Author: r.Author, URL: r.URL}
+}
+
+func rank(c Change) int {
+	if c.NewRank != 0 {
+		return c.NewRank
+	}
+	return c.OldRank
+}
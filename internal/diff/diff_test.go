@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/output"
+)
+
+func TestCompare(t *testing.T) {
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	old := []output.Record{
+		{Rank: 1, Title: "a", Comments: 10},
+		{Rank: 2, Title: "b", Comments: 8},
+		{Rank: 3, Title: "c", Comments: 5, Author: "x"},
+		{Rank: 4, Title: "d", Comments: 3, CreatedAt: at},
+	}
+
+	tests := []struct {
+		name string
+		new  []output.Record
+		want []Change
+	}{
+		{
+			name: "no changes",
+			new:  old,
+			want: []Change{},
+		},
+		{
+			name: "all change types",
+			new: []output.Record{
+				{Rank: 1, Title: "b", Comments: 12},
+				{Rank: 2, Title: "a", Comments: 10},
+				{Rank: 3, Title: "c", Comments: 6, Author: "x"},
+				{Rank: 4, Title: "e", Comments: 4},
+			},
+			want: []Change{
+				{Type: Entered, Title: "e", NewRank: 4, NewComments: 4, CommentsDelta: 4},
+				{Type: Left, Title: "d", OldRank: 4, OldComments: 3, CommentsDelta: -3},
+				{Type: Moved, Title: "b", OldRank: 2, NewRank: 1, OldComments: 8, NewComments: 12, CommentsDelta: 4},
+				{Type: Moved, Title: "a", OldRank: 1, NewRank: 2, OldComments: 10, NewComments: 10},
+				{Type: Comments, Title: "c", Author: "x", OldRank: 3, NewRank: 3, OldComments: 5, NewComments: 6, CommentsDelta: 1},
+			},
+		},
+		{
+			name: "the same title of another author is a different article",
+			new: []output.Record{
+				{Rank: 1, Title: "a", Comments: 10},
+				{Rank: 2, Title: "b", Comments: 8},
+				{Rank: 3, Title: "c", Comments: 5, Author: "y"},
+				{Rank: 4, Title: "d", Comments: 3, CreatedAt: at},
+			},
+			want: []Change{
+				{Type: Entered, Title: "c", Author: "y", NewRank: 3, NewComments: 5, CommentsDelta: 5},
+				{Type: Left, Title: "c", Author: "x", OldRank: 3, OldComments: 5, CommentsDelta: -5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(old, tt.new)
+			assert.Equal(t, tt.want, got.Changes)
+			assert.Equal(t, len(tt.want) == 0, got.Empty())
+		})
+	}
+}
+
+func TestResult_Count(t *testing.T) {
+	r := Compare(nil, []output.Record{{Rank: 1, Title: "a"}, {Rank: 2, Title: "b"}})
+	assert.Equal(t, 2, r.Count(Entered))
+	assert.Equal(t, 0, r.Count(Left))
+}
+
+func TestResult_Summary(t *testing.T) {
+	old := []output.Record{{Rank: 1, Title: "a"}, {Rank: 2, Title: "b"}, {Rank: 3, Title: "c"}, {Rank: 4, Title: "d"}}
+	cur := []output.Record{{Rank: 1, Title: "b"}, {Rank: 2, Title: "a"}, {Rank: 3, Title: "e"}, {Rank: 4, Title: "f"}}
+
+	assert.Equal(t, Summary{Entered: 2, Left: 2, Moved: 2, Churn: 0.5}, Compare(old, cur).Summary())
+	assert.Equal(t, Summary{Moved: 2}, Compare(Top(old, 2), Top(cur, 2)).Summary())
+	assert.Equal(t, Summary{}, Compare(nil, nil).Summary())
+}
+
+func TestLoad(t *testing.T) {
+	want := []output.Record{{Rank: 1, Title: "a", Comments: 10}, {Rank: 2, Title: "b", Comments: 8}}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []output.Record
+	}{
+		{
+			name:  "json",
+			input: `[{"rank":1,"title":"a","comments":10},{"rank":2,"title":"b","comments":8}]`,
+			want:  want,
+		},
+		{
+			name:  "ndjson",
+			input: "{\"rank\":1,\"title\":\"a\",\"comments\":10}\n{\"rank\":2,\"title\":\"b\",\"comments\":8}\n",
+			want:  want,
+		},
+		{
+			name:  "run",
+			input: `{"id":"20240102T000000Z-1","top":[{"rank":1,"title":"a","comments":10},{"rank":2,"title":"b","comments":8}]}`,
+			want:  want,
+		},
+		{
+			name:  "text",
+			input: "a\n\nb\n",
+			want:  []output.Record{{Rank: 1, Title: "a"}, {Rank: 2, Title: "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := Load(strings.NewReader(" \n"))
+	require.Error(t, err)
+	_, err = Load(strings.NewReader("[{"))
+	require.Error(t, err)
+}
+
+func TestWriteText(t *testing.T) {
+	old := []output.Record{{Rank: 1, Title: "a", Comments: 10}, {Rank: 2, Title: "b", Comments: 8}}
+	cur := []output.Record{{Rank: 1, Title: "b", Comments: 12}, {Rank: 2, Title: "c", Comments: 9}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteText(&buf, Compare(old, cur)))
+	assert.Equal(t, "+ #2\tc (9 comments)\n"+
+		"- #1\ta (10 comments)\n"+
+		"~ #2 -> #1\tb (+4 comments)\n"+
+		"entered 1, left 1, moved 1, comments 0, churn 50%\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteText(&buf, Compare(old, old)))
+	assert.Equal(t, "no changes\n", buf.String())
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"articles-service/internal/output"
+)
+
+// LoadFile reads the top list from the file, "-" reads stdin.
+func LoadFile(path string) ([]output.Record, error) {
+	if path == "-" {
+		return Load(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return records, nil
+}
+
+// Load reads the top list saved by the service:
+//   - json output(array of records)
+//   - ndjson output(record per line)
+//   - run of the server(GET /v1/runs/{id}), its top is used
+//   - text output, title per line ranked by the line number
+func Load(r io.Reader) ([]output.Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case len(data) == 0:
+		return nil, errors.New("empty top list")
+	case data[0] == '[':
+		var records []output.Record
+		if err = json.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	case data[0] == '{':
+		return loadObjects(data)
+	default:
+		return loadText(data), nil
+	}
+}
+
+// loadObjects reads either one run or ndjson records.
+func loadObjects(data []byte) ([]output.Record, error) {
+	var records []output.Record
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var v struct {
+			output.Record
+			Top []output.Record `json:"top"`
+		}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if v.Top != nil {
+			return v.Top, nil
+		}
+		records = append(records, v.Record)
+	}
+
+	return records, nil
+}
+
+func loadText(data []byte) []output.Record {
+	var records []output.Record
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		title := strings.TrimSpace(sc.Text())
+		if title == "" {
+			continue
+		}
+		records = append(records, output.Record{Rank: len(records) + 1, Title: title})
+	}
+
+	return records
+}
+
+// WriteJSON writes the summary and the changes as one JSON object.
+func WriteJSON(w io.Writer, r Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Summary Summary  `json:"summary"`
+		Changes []Change `json:"changes"`
+	}{r.Summary(), r.Changes})
+}
+
+// WriteText writes a change per line and the summary line.
+func WriteText(w io.Writer, r Result) error {
+	bw := bufio.NewWriter(w)
+
+	for _, c := range r.Changes {
+		switch c.Type {
+		case Entered:
+			fmt.Fprintf(bw, "+ #%d\t%s (%d comments)\n", c.NewRank, c.Title, c.NewComments)
+		case Left:
+			fmt.Fprintf(bw, "- #%d\t%s (%d comments)\n", c.OldRank, c.Title, c.OldComments)
+		case Moved:
+			fmt.Fprintf(bw, "~ #%d -> #%d\t%s (%+d comments)\n", c.OldRank, c.NewRank, c.Title, c.CommentsDelta)
+		case Comments:
+			fmt.Fprintf(bw, "= #%d\t%s (%+d comments)\n", c.NewRank, c.Title, c.CommentsDelta)
+		}
+	}
+
+	s := r.Summary()
+	if r.Empty() {
+		fmt.Fprintln(bw, "no changes")
+	} else {
+		fmt.Fprintf(bw, "entered %d, left %d, moved %d, comments %d, churn %.0f%%\n",
+			s.Entered, s.Left, s.Moved, s.Comments, s.Churn*100)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"articles-service/internal/diff"
+	"articles-service/internal/output"
+	"articles-service/internal/snapshot"
+)
+
+// ErrChurnExceeded is returned by the diff command when the churn of the top list
+// is above -max-churn, to alert on it from cron/CI.
+var ErrChurnExceeded = errors.New("churn exceeded")
+
+// diffFlags are the flags of the diff command.
+type diffFlags struct {
+	format   string
+	top      int
+	maxChurn float64
+	output   string
+	limit    int
+	dir      string
+	config   string
+}
+
+// newDiffFlagSet defines the flags of the diff command, the completion walks them too.
+func newDiffFlagSet(f *diffFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.StringVar(&f.format, "format", "text", "output format: text|json")
+	fs.IntVar(&f.top, "top", 0, "compare only the top N of both lists(0 - all)")
+	fs.Float64Var(&f.maxChurn, "max-churn", 0, "fail with exit code 2 when the churn is above(0..1, 0 - disabled)")
+	fs.StringVar(&f.output, "output", "", "write the diff to the file(atomically) instead of stdout")
+	fs.IntVar(&f.limit, "l", 0, "limit of the current run when NEW is omitted, default the size of OLD")
+	fs.StringVar(&f.dir, "snapshot-dir", defaultSnapshotDir, "snapshot store directory of @ID references")
+	fs.StringVar(&f.config, "config", "", "config file of the current run, default from "+envConfig)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: top-articles diff [flags] OLD [NEW]")
+		fmt.Fprintln(fs.Output(), "OLD and NEW are json/ndjson/text outputs or runs of the server, \"-\" reads stdin,")
+		fmt.Fprintln(fs.Output(), "@ID, @latest or @latest~N refer to the stored snapshots,")
+		fmt.Fprintln(fs.Output(), "the current run is compared with OLD when NEW is omitted.")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// RunDiff runs the diff command: compares two saved top lists
+// or the saved one against the current run.
+//
+//	diff [flags] OLD [NEW]
+func RunDiff(ctx context.Context, argv []string) error {
+	var f diffFlags
+	fs := newDiffFlagSet(&f)
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return errors.New("diff expects one or two top lists")
+	}
+
+	var write func(w io.Writer, r diff.Result) error
+	switch f.format {
+	case "text":
+		write = diff.WriteText
+	case "json":
+		write = diff.WriteJSON
+	default:
+		return fmt.Errorf("unknown diff format %q, expected one of: text, json", f.format)
+	}
+
+	old, err := loadTop(fs.Arg(0), f.dir)
+	if err != nil {
+		return fmt.Errorf("load old top list: %w", err)
+	}
+
+	var cur []output.Record
+	if fs.NArg() == 2 {
+		if cur, err = loadTop(fs.Arg(1), f.dir); err != nil {
+			return fmt.Errorf("load new top list: %w", err)
+		}
+	} else {
+		n := f.limit
+		if n == 0 {
+			n = min(len(old), MaxLimit)
+		}
+		if cur, err = currentTop(ctx, n, f.config); err != nil {
+			return err
+		}
+	}
+
+	res := diff.Compare(diff.Top(old, f.top), diff.Top(cur, f.top))
+	err = output.WriteFile(f.output, func(w io.Writer) error {
+		return write(w, res)
+	})
+	if err != nil {
+		return fmt.Errorf("write diff: %w", err)
+	}
+
+	if churn := res.Summary().Churn; f.maxChurn > 0 && churn > f.maxChurn {
+		return fmt.Errorf("%w: %.2f > %.2f", ErrChurnExceeded, churn, f.maxChurn)
+	}
+
+	return nil
+}
+
+// loadTop reads the top list from the file or the snapshot store(@ID).
+func loadTop(ref, dir string) ([]output.Record, error) {
+	id, ok := strings.CutPrefix(ref, "@")
+	if !ok {
+		return diff.LoadFile(ref)
+	}
+
+	store, err := snapshot.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Top, nil
+}
+
+// currentTop runs one crawl of the top articles with the settings of the top command:
+// the config file, ARTICLES_* env and defaults.
+func currentTop(ctx context.Context, limit int, config string) ([]output.Record, error) {
+	if limit <= 0 || limit > MaxLimit {
+		return nil, fmt.Errorf("limit of the current run is out of range: 1..%d", MaxLimit)
+	}
+
+	argv := []string{"-l=" + strconv.Itoa(limit)}
+	if config != "" {
+		argv = append(argv, "-config="+config)
+	}
+	app, err := newCommandApp(cmdTop, argv)
+	if err != nil {
+		return nil, err
+	}
+	defer app.Close()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	run, err := app.crawl(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("current run: %w", err)
+	}
+
+	return run.Top, nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/diff"
+)
+
+// newUpstream serves one page of articles with the comments by the title.
+func newUpstream(t *testing.T, comments map[string]int) *httptest.Server {
+	t.Helper()
+	resp := articlesapi.Response{Page: 1, PerPage: len(comments), Total: len(comments), TotalPages: 1}
+	for title, n := range comments {
+		title, n := title, n
+		resp.Data = append(resp.Data, &articlesapi.Article{Title: &title, NumComments: &n})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunDiff_CurrentRun(t *testing.T) {
+	srv := newUpstream(t, map[string]int{"a": 5, "b": 10})
+	t.Setenv("ARTICLES_API_URL", srv.URL)
+	t.Setenv("ARTICLES_LOG_LEVEL", "error")
+
+	dir := t.TempDir()
+	old, out := filepath.Join(dir, "old.txt"), filepath.Join(dir, "diff.json")
+	require.NoError(t, os.WriteFile(old, []byte("a\nb\n"), 0o644))
+
+	// the current run is crawled with the settings of the top command
+	require.NoError(t, RunDiff(context.Background(), []string{"-format=json", "-output=" + out, old}))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var res struct {
+		Changes []diff.Change `json:"changes"`
+	}
+	require.NoError(t, json.Unmarshal(data, &res))
+	require.Len(t, res.Changes, 2)
+	for _, c := range res.Changes {
+		assert.Equal(t, diff.Moved, c.Type, c.Title)
+	}
+}
+
+func TestRunDiff_CurrentRun_RateLimit(t *testing.T) {
+	srv := newUpstream(t, map[string]int{"a": 5})
+	t.Setenv("ARTICLES_API_URL", srv.URL)
+	t.Setenv("ARTICLES_LOG_LEVEL", "error")
+
+	old := filepath.Join(t.TempDir(), "old.txt")
+	require.NoError(t, os.WriteFile(old, []byte("a\n"), 0o644))
+	argv := []string{"-output=" + filepath.Join(t.TempDir(), "diff.txt"), old}
+
+	// the default rate limit passes the validation of the current run
+	require.NoError(t, RunDiff(context.Background(), argv))
+
+	// and the configured one is validated like in the top command
+	t.Setenv("ARTICLES_BURST", "0")
+	assert.ErrorIs(t, RunDiff(context.Background(), argv), ErrInvalidArgs)
+}
@@ -0,0 +1,70 @@
+package domains
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Host extracts the normalized host from an article url:
+// lowercase, without port, trailing dot and "www." prefix.
+// Returns an empty string when the url has no host.
+func Host(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	// urls without scheme ("example.com/path") are parsed as a path
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	host = strings.TrimPrefix(host, "www.")
+
+	return host
+}
+
+// Registrable collapses a host to its registrable domain(eTLD+1)
+// using the built-in public suffix table, e.g. "news.bbc.co.uk" -> "bbc.co.uk".
+// IP addresses and hosts that are a public suffix themselves are returned as is.
+func Registrable(host string) string {
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return host
+	}
+
+	// by default the public suffix is the last label(TLD),
+	// the longest matching suffix from the table wins
+	suffixLen := 1
+	for i := 0; i < len(labels)-1; i++ {
+		if _, ok := publicSuffixes[strings.Join(labels[i:], ".")]; ok {
+			suffixLen = len(labels) - i
+			break
+		}
+	}
+
+	if suffixLen >= len(labels) {
+		return host
+	}
+
+	return strings.Join(labels[len(labels)-suffixLen-1:], ".")
+}
+
+// Domain returns the host of the url collapsed to the registrable domain when requested.
+func Domain(rawURL string, registrable bool) string {
+	host := Host(rawURL)
+	if registrable {
+		return Registrable(host)
+	}
+	return host
+}
@@ -0,0 +1,62 @@
+package domains
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "empty url", url: "", want: ""},
+		{name: "lowercase and strip www", url: "https://WWW.Example.COM/path?q=1", want: "example.com"},
+		{name: "strip port", url: "http://news.example.com:8080/a", want: "news.example.com"},
+		{name: "strip trailing dot", url: "http://example.com./a", want: "example.com"},
+		{name: "without scheme", url: "www.example.org/article", want: "example.org"},
+		{name: "only www is stripped", url: "https://www2.example.com", want: "www2.example.com"},
+		{name: "ip address", url: "http://127.0.0.1:80/", want: "127.0.0.1"},
+		{name: "invalid url", url: "http://%zz", want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Host(tt.url))
+		})
+	}
+}
+
+func TestRegistrable(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{name: "empty host", host: "", want: ""},
+		{name: "single label", host: "localhost", want: "localhost"},
+		{name: "already registrable", host: "example.com", want: "example.com"},
+		{name: "subdomain of tld", host: "blog.news.example.com", want: "example.com"},
+		{name: "multi label suffix", host: "news.bbc.co.uk", want: "bbc.co.uk"},
+		{name: "public suffix itself", host: "co.uk", want: "co.uk"},
+		{name: "private registry", host: "user.github.io", want: "user.github.io"},
+		{name: "private registry subdomain", host: "docs.user.github.io", want: "user.github.io"},
+		{name: "ip address", host: "10.0.0.1", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Registrable(tt.host))
+		})
+	}
+}
+
+func TestDomain(t *testing.T) {
+	assert.Equal(t, "news.bbc.co.uk", Domain("https://www.news.bbc.co.uk/x", false))
+	assert.Equal(t, "bbc.co.uk", Domain("https://www.news.bbc.co.uk/x", true))
+	assert.Equal(t, "", Domain("", true))
+}
@@ -0,0 +1,36 @@
+package domains
+
+// todo: the full list is https://publicsuffix.org/list/public_suffix_list.dat
+// (~10k rules) and better to generate it with go:generate,
+// for the leaderboard the most popular multi-label suffixes are enough.
+
+// publicSuffixes contains multi-label public suffixes,
+// single-label TLDs are handled without the table.
+var publicSuffixes = map[string]struct{}{
+	// United Kingdom
+	"co.uk": {}, "org.uk": {}, "ac.uk": {}, "gov.uk": {}, "me.uk": {}, "ltd.uk": {}, "plc.uk": {}, "net.uk": {},
+	// Australia, New Zealand
+	"com.au": {}, "net.au": {}, "org.au": {}, "edu.au": {}, "gov.au": {},
+	"co.nz": {}, "net.nz": {}, "org.nz": {}, "ac.nz": {}, "govt.nz": {},
+	// Asia
+	"co.jp": {}, "ne.jp": {}, "or.jp": {}, "ac.jp": {}, "go.jp": {},
+	"co.kr": {}, "or.kr": {}, "ac.kr": {},
+	"com.cn": {}, "net.cn": {}, "org.cn": {}, "edu.cn": {}, "gov.cn": {},
+	"com.hk": {}, "org.hk": {}, "com.tw": {}, "org.tw": {}, "edu.tw": {},
+	"com.sg": {}, "edu.sg": {}, "gov.sg": {},
+	"co.in": {}, "net.in": {}, "org.in": {}, "ac.in": {}, "gov.in": {},
+	"co.id": {}, "ac.id": {}, "com.my": {}, "com.ph": {}, "co.th": {}, "ac.th": {}, "com.vn": {},
+	"co.il": {}, "org.il": {}, "ac.il": {}, "com.tr": {}, "org.tr": {},
+	// Americas
+	"com.br": {}, "net.br": {}, "org.br": {}, "gov.br": {},
+	"com.mx": {}, "org.mx": {}, "com.ar": {}, "com.co": {}, "com.pe": {}, "gc.ca": {},
+	// Europe, Africa
+	"co.za": {}, "org.za": {}, "ac.za": {},
+	"com.ua": {}, "org.ua": {}, "com.pl": {}, "net.pl": {}, "co.at": {}, "or.at": {},
+	"com.es": {}, "com.gr": {}, "com.ru": {}, "org.ru": {},
+	// private registries: every subdomain belongs to a different owner
+	"github.io": {}, "gitlab.io": {}, "blogspot.com": {}, "herokuapp.com": {},
+	"appspot.com": {}, "netlify.app": {}, "vercel.app": {}, "pages.dev": {}, "workers.dev": {},
+	"cloudfront.net": {}, "azurewebsites.net": {}, "s3.amazonaws.com": {},
+	"readthedocs.io": {}, "neocities.org": {},
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal/output"
+	"articles-service/internal/report"
+)
+
+type (
+	// stateDump is the live state of the app written on SIGUSR1.
+	stateDump struct {
+		Time time.Time `json:"time"`
+		Mode string    `json:"mode"`
+		// the current or the last run
+		RunID         string     `json:"run_id,omitempty"`
+		Running       bool       `json:"running"`
+		Pages         dumpPages  `json:"pages"`
+		LimiterTokens float64    `json:"limiter_tokens"`
+		Errors        dumpErrors `json:"errors"`
+		// top collected so far by the running crawl or the top of the last successful run
+		Top      []output.Record `json:"top"`
+		TopRunID string          `json:"top_run_id,omitempty"`
+	}
+	dumpPages struct {
+		Total     int `json:"total"`
+		Done      int `json:"done"`
+		Remaining int `json:"remaining"`
+		InFlight  int `json:"in_flight"`
+	}
+	dumpErrors struct {
+		PagesFailed int                       `json:"pages_failed"`
+		Retries     int                       `json:"retries"`
+		RowsDropped map[report.DropReason]int `json:"rows_dropped"`
+	}
+	// liveRun is the run in progress for state dumps.
+	liveRun struct {
+		id  string
+		rec *report.Recorder
+	}
+)
+
+// dumpState writes the live state without stopping the app, called by the signal dispatcher.
+func (a *App) dumpState() {
+	st := a.proc.State()
+	dump := stateDump{
+		Time:    time.Now().UTC(),
+		Mode:    a.mode(),
+		Running: st.Running,
+		Pages: dumpPages{
+			Total:     st.TotalPages,
+			Done:      st.PagesDone,
+			Remaining: max(st.TotalPages-st.PagesDone, 0),
+			InFlight:  st.InFlight,
+		},
+		LimiterTokens: st.LimiterTokens,
+		Top:           output.Articles(st.Top),
+	}
+	// the storage is emptied by the finished crawl
+	if !st.Running {
+		if run := a.current.Load(); run != nil {
+			dump.Top, dump.TopRunID = run.Top, run.ID
+		}
+	}
+	if live := a.live.Load(); live != nil {
+		rep := live.rec.Report(nil)
+		dump.RunID = live.id
+		dump.Errors = dumpErrors{
+			PagesFailed: rep.Pages.Failed,
+			Retries:     rep.Pages.Retried,
+			RowsDropped: rep.Rows.Dropped,
+		}
+	}
+
+	write := func(w io.Writer) error {
+		return writeIndentedJSON(w, dump)
+	}
+	var err error
+	if a.args.dumpFile == "" {
+		err = write(os.Stderr)
+	} else {
+		err = output.WriteFile(a.args.dumpFile, write)
+	}
+	if err != nil {
+		a.logger.Error("cannot write the state dump", zap.Error(err))
+		return
+	}
+	a.logger.Info("state dumped", zap.String("run_id", dump.RunID), zap.Int("pages_done", dump.Pages.Done))
+}
+
+func (a *App) mode() string {
+	switch {
+	case a.args.serve:
+		return "serve"
+	case a.args.watch:
+		return "watch"
+	default:
+		return "one-shot"
+	}
+}
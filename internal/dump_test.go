@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"articles-service/internal/output"
+	"articles-service/internal/runs"
+)
+
+func TestApp_DumpState_BetweenRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.json")
+	args, err := parseArgs([]string{"-watch", "-l=2", "-dump-file=" + path})
+	require.NoError(t, err)
+	app, err := newApp(zap.NewNop(), args)
+	require.NoError(t, err)
+
+	read := func() stateDump {
+		t.Helper()
+		app.dumpState()
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var dump stateDump
+		require.NoError(t, json.Unmarshal(data, &dump))
+		return dump
+	}
+
+	dump := read()
+	assert.Equal(t, "watch", dump.Mode)
+	assert.Empty(t, dump.Top, "nothing finished yet")
+
+	top := []output.Record{{Rank: 1, Title: "a", Comments: 10}, {Rank: 2, Title: "b", Comments: 5}}
+	app.current.Store(&runs.Run{ID: "run-1", Top: top})
+
+	dump = read()
+	assert.False(t, dump.Running)
+	assert.Equal(t, top, dump.Top, "the last run is dumped while no crawl is in flight")
+	assert.Equal(t, "run-1", dump.TopRunID)
+}
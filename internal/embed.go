@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/output"
+	"articles-service/internal/runs"
+	"articles-service/internal/storage"
+)
+
+// Options are the settings of the app embedded into other programs, see pkg/articles.
+type Options struct {
+	Limit int
+	// articles api endpoint, the default one when empty
+	Source string
+	// persist every run into the snapshot store directory when set
+	SnapshotDir string
+	// format of the top written by Crawl, text when empty
+	Format output.Format
+}
+
+// args converts the options into the args of the top command with the flag defaults.
+func (o Options) args() args {
+	a := args{
+		command:     cmdTop,
+		limit:       o.Limit,
+		api:         articlesapi.DefaultConfig(),
+		format:      cmp.Or(o.Format, output.Text),
+		domainRank:  storage.RankByComments,
+		snapshotDir: o.SnapshotDir,
+	}
+	if o.Source != "" {
+		a.api.BaseURL = o.Source
+	}
+	return a
+}
+
+// NewEmbedded builds the app running crawls in-process, invalid options are returned as ErrInvalidArgs.
+func NewEmbedded(logger *zap.Logger, opts Options) (*App, error) {
+	return newApp(logger, opts.args())
+}
+
+// Crawl runs one crawl of the embedded app, must not be called concurrently.
+// The run is persisted like the one-shot run and its top is written to w when not nil.
+func (a *App) Crawl(ctx context.Context, w io.Writer) (*runs.Run, error) {
+	run, err := a.crawl(ctx)
+	// interrupted run is incomplete, nothing to keep
+	if ctx.Err() == nil {
+		a.persist(run)
+	}
+	if err = interrupted(ctx, err, false); err != nil {
+		return nil, err
+	}
+
+	if w != nil {
+		if err = a.writer.Write(w, a.render(run)); err != nil {
+			return nil, fmt.Errorf("write result: %w", err)
+		}
+	}
+	return run, nil
+}
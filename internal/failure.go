@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"articles-service/internal/articlesapi"
+)
+
+// exit codes of the process, the orchestrator decides on retries by them
+const (
+	ExitOK       = 0
+	ExitInternal = 1
+	// the diff command only
+	ExitChurnExceeded = 2
+	ExitPartial       = 3
+	// sysexits.h: EX_USAGE, EX_UNAVAILABLE, EX_TEMPFAIL
+	ExitInvalidArgs = 64
+	ExitUnavailable = 69
+	ExitRateLimited = 75
+	// 128 + SIGINT
+	ExitInterrupted = 130
+)
+
+// error formats of -error-format
+const (
+	ErrorText = "text"
+	ErrorJSON = "json"
+)
+
+var (
+	// ErrInvalidArgs marks errors of flags, the config file and env.
+	ErrInvalidArgs = errors.New("invalid arguments")
+	// ErrInterrupted is returned by Run when the run was stopped by a signal,
+	// the caller context or -timeout without -partial.
+	ErrInterrupted = errors.New("interrupted")
+)
+
+// Failure is the classified error of the process written to stderr by -error-format=json.
+type Failure struct {
+	// stable name of the failure type, e.g. upstream_unavailable
+	Code     string `json:"code"`
+	ExitCode int    `json:"exit_code"`
+	Message  string `json:"message"`
+	// pages failed after all retries
+	FailedPages []int `json:"failed_pages,omitempty"`
+	// messages of the wrapped errors from the outermost one
+	Causes []string `json:"causes,omitempty"`
+}
+
+// NewFailure classifies the error.
+func NewFailure(err error) *Failure {
+	f := &Failure{Message: err.Error(), Causes: causes(err)}
+	switch {
+	case errors.Is(err, ErrPartial):
+		f.Code, f.ExitCode = "partial", ExitPartial
+	case errors.Is(err, ErrInvalidArgs):
+		f.Code, f.ExitCode = "invalid_arguments", ExitInvalidArgs
+	case errors.Is(err, ErrInterrupted):
+		f.Code, f.ExitCode = "interrupted", ExitInterrupted
+	case errors.Is(err, ErrChurnExceeded):
+		f.Code, f.ExitCode = "churn_exceeded", ExitChurnExceeded
+	case articlesapi.RateLimited(err):
+		f.Code, f.ExitCode = "rate_limited", ExitRateLimited
+	case articlesapi.Unavailable(err):
+		f.Code, f.ExitCode = "upstream_unavailable", ExitUnavailable
+	default:
+		f.Code, f.ExitCode = "internal", ExitInternal
+	}
+	return f
+}
+
+// Write writes the failure as JSON or as the text line.
+func (f *Failure) Write(w io.Writer, format string) error {
+	if format == ErrorJSON {
+		return json.NewEncoder(w).Encode(f)
+	}
+	_, err := fmt.Fprintf(w, "%s: %s\n", f.Code, f.Message)
+	return err
+}
+
+// Fail writes the failure of the app initialization to stderr and returns the exit code,
+// -error-format is taken from argv or env since the config could not be loaded.
+func Fail(argv []string, err error) int {
+	f := NewFailure(err)
+	_ = f.Write(os.Stderr, errorFormat(argv, os.Getenv(envPrefix+"ERROR_FORMAT")))
+	return f.ExitCode
+}
+
+// Fail logs the error of Run, writes it by -error-format and returns the exit code.
+func (a *App) Fail(err error) int {
+	f := NewFailure(err)
+	if a.last != nil {
+		f.FailedPages = a.last.Report.Pages.FailedPages
+	}
+
+	if f.ExitCode == ExitPartial {
+		a.logger.Sugar().Warnf("articles service stopped with partial result: %v", err)
+	} else {
+		a.logger.Sugar().Errorf("articles service stopped with error: %v", err)
+	}
+	if a.args.errorFormat == ErrorJSON {
+		_ = f.Write(os.Stderr, ErrorJSON)
+	}
+	return f.ExitCode
+}
+
+// causes returns messages of the error chain below err, joined errors are walked depth first.
+func causes(err error) []string {
+	var res []string
+	var walk func(err error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			if next := e.Unwrap(); next != nil {
+				res = append(res, next.Error())
+				walk(next)
+			}
+		case interface{ Unwrap() []error }:
+			for _, next := range e.Unwrap() {
+				res = append(res, next.Error())
+				walk(next)
+			}
+		}
+	}
+	walk(err)
+	return res
+}
+
+// errorFormat finds -error-format in the command line falling back to env,
+// the command line may be invalid, so it is not parsed.
+func errorFormat(argv []string, env string) string {
+	format := env
+	for i, arg := range argv {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "error-format" {
+			continue
+		}
+		if !hasValue && i+1 < len(argv) {
+			value = argv[i+1]
+		}
+		format = value
+	}
+	return format
+}
+
+// invalidArgs marks the error of the arguments with ErrInvalidArgs, help is not an error.
+func invalidArgs(err error) error {
+	if err == nil || errors.Is(err, flag.ErrHelp) || errors.Is(err, ErrInvalidArgs) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidArgs, err)
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/articlesprocessor"
+)
+
+func TestNewFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+		wantExit int
+	}{
+		{name: "partial", err: &articlesprocessor.PartialError{Cause: context.Canceled}, wantCode: "partial", wantExit: ExitPartial},
+		{name: "invalid arguments", err: invalidArgs(errors.New("please provide limit")), wantCode: "invalid_arguments", wantExit: ExitInvalidArgs},
+		{name: "interrupted", err: fmt.Errorf("%w: %w", ErrInterrupted, context.Canceled), wantCode: "interrupted", wantExit: ExitInterrupted},
+		{name: "rate limited", err: fmt.Errorf("page: %w", &articlesapi.StatusError{Page: 2, StatusCode: 429}), wantCode: "rate_limited", wantExit: ExitRateLimited},
+		{name: "5xx", err: &articlesapi.StatusError{Page: 2, StatusCode: 503}, wantCode: "upstream_unavailable", wantExit: ExitUnavailable},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("refused")}, wantCode: "upstream_unavailable", wantExit: ExitUnavailable},
+		{name: "canceled", err: context.Canceled, wantCode: "internal", wantExit: ExitInternal},
+		{name: "internal", err: errors.New("disk full"), wantCode: "internal", wantExit: ExitInternal},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFailure(tt.err)
+			assert.Equal(t, tt.wantCode, f.Code)
+			assert.Equal(t, tt.wantExit, f.ExitCode)
+		})
+	}
+}
+
+func TestFailure_WriteJSON(t *testing.T) {
+	err := fmt.Errorf("ProcessArticles error: %w", &articlesapi.StatusError{Page: 2, StatusCode: 503})
+	f := NewFailure(err)
+	f.FailedPages = []int{2}
+
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf, ErrorJSON))
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, map[string]any{
+		"code":         "upstream_unavailable",
+		"exit_code":    float64(ExitUnavailable),
+		"message":      "ProcessArticles error: unexpected status 503 for page 2",
+		"failed_pages": []any{float64(2)},
+		"causes":       []any{"unexpected status 503 for page 2"},
+	}, got)
+}
+
+func TestErrorFormat(t *testing.T) {
+	assert.Equal(t, "", errorFormat([]string{"-l=5"}, ""))
+	assert.Equal(t, "json", errorFormat([]string{"-l=5"}, "json"))
+	assert.Equal(t, "json", errorFormat([]string{"-l=500", "--error-format=json"}, "text"))
+	assert.Equal(t, "json", errorFormat([]string{"-error-format", "json", "-l"}, ""))
+}
@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Atom 1.0: https://www.rfc-editor.org/rfc/rfc4287
+type (
+	atomDoc struct {
+		XMLName   xml.Name    `xml:"feed"`
+		Namespace string      `xml:"xmlns,attr"`
+		ID        string      `xml:"id"`
+		Title     string      `xml:"title"`
+		Subtitle  string      `xml:"subtitle,omitempty"`
+		Updated   string      `xml:"updated"`
+		Links     []atomLink  `xml:"link"`
+		Author    atomPerson  `xml:"author"`
+		Generator string      `xml:"generator"`
+		Entries   []atomEntry `xml:"entry"`
+	}
+	atomEntry struct {
+		ID        string      `xml:"id"`
+		Title     string      `xml:"title"`
+		Updated   string      `xml:"updated"`
+		Published string      `xml:"published,omitempty"`
+		Links     []atomLink  `xml:"link,omitempty"`
+		Author    *atomPerson `xml:"author,omitempty"`
+		Summary   string      `xml:"summary"`
+		// required when there is no alternate link(RFC 4287 4.1.1)
+		Content *atomContent `xml:"content,omitempty"`
+	}
+	atomContent struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	}
+	atomLink struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr,omitempty"`
+		Type string `xml:"type,attr,omitempty"`
+	}
+	atomPerson struct {
+		Name string `xml:"name"`
+	}
+)
+
+func newAtom(meta Meta, items []Item) atomDoc {
+	updated := meta.Updated.Format(time.RFC3339)
+
+	doc := atomDoc{
+		Namespace: atomNamespace,
+		ID:        meta.Link,
+		Title:     meta.Title,
+		Updated:   updated,
+		Links:     []atomLink{{Href: meta.Link, Rel: "alternate"}},
+		// feed level author is required unless every entry has one
+		Author:    atomPerson{Name: generator},
+		Generator: generator,
+		Entries:   make([]atomEntry, len(items)),
+	}
+	if meta.Description != meta.Title {
+		doc.Subtitle = meta.Description
+	}
+	if meta.SelfLink != "" {
+		doc.ID = meta.SelfLink
+		doc.Links = append(doc.Links, atomLink{Href: meta.SelfLink, Rel: "self", Type: "application/atom+xml"})
+	}
+
+	for i, it := range items {
+		id, _ := it.id()
+		e := atomEntry{
+			ID:      id,
+			Title:   it.Title,
+			Updated: updated,
+			Summary: it.description(),
+		}
+		if !it.Published.IsZero() {
+			e.Published = it.Published.UTC().Format(time.RFC3339)
+			e.Updated = e.Published
+		}
+		if it.Link != "" {
+			e.Links = []atomLink{{Href: it.Link, Rel: "alternate"}}
+		} else {
+			e.Content = &atomContent{Type: "text", Value: e.Summary}
+		}
+		if it.Author != "" {
+			e.Author = &atomPerson{Name: it.Author}
+		}
+		doc.Entries[i] = e
+	}
+
+	return doc
+}
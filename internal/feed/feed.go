@@ -0,0 +1,112 @@
+package feed
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	RSS  Kind = "rss"
+	Atom Kind = "atom"
+
+	generator     = "articles-service"
+	defaultTitle  = "Top discussed articles"
+	defaultLink   = "https://jsonmock.hackerrank.com/api/articles"
+	atomNamespace = "http://www.w3.org/2005/Atom"
+	dcNamespace   = "http://purl.org/dc/elements/1.1/"
+)
+
+type (
+	Kind string
+	// Meta describes the feed itself.
+	Meta struct {
+		Title       string
+		Description string
+		// Link is the site the feed is about
+		Link string
+		// SelfLink is the url the feed is served from, optional
+		SelfLink string
+		// Updated is the time of the feed generation, now by default
+		Updated time.Time
+	}
+	// Item is one article of the feed.
+	Item struct {
+		Title     string
+		Link      string
+		Author    string
+		Comments  uint64
+		Published time.Time
+	}
+)
+
+func (m Meta) withDefaults() Meta {
+	if m.Title == "" {
+		m.Title = defaultTitle
+	}
+	if m.Description == "" {
+		m.Description = m.Title
+	}
+	if m.Link == "" {
+		m.Link = defaultLink
+	}
+	if m.Updated.IsZero() {
+		m.Updated = time.Now()
+	}
+	m.Updated = m.Updated.UTC()
+	return m
+}
+
+// Write renders the feed of the kind.
+func Write(w io.Writer, kind Kind, meta Meta, items []Item) error {
+	meta = meta.withDefaults()
+
+	var doc any
+	switch kind {
+	case RSS:
+		doc = newRSS(meta, items)
+	case Atom:
+		doc = newAtom(meta, items)
+	default:
+		return fmt.Errorf("unknown feed kind %q", kind)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode %s feed: %w", kind, err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ContentType returns the media type of the feed kind.
+func ContentType(kind Kind) string {
+	if kind == Atom {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+func (it Item) description() string {
+	return strconv.FormatUint(it.Comments, 10) + " comments"
+}
+
+// id returns the permanent id of the item: the link or
+// a name based uuid(v5 layout) when upstream has no url.
+func (it Item) id() (string, bool) {
+	if it.Link != "" {
+		return it.Link, true
+	}
+
+	h := sha1.Sum([]byte(it.Title + "\x00" + it.Author + "\x00" + strconv.FormatInt(it.Published.Unix(), 10)))
+	h[6] = (h[6] & 0x0f) | 0x50
+	h[8] = (h[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16]), false
+}
@@ -0,0 +1,230 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var (
+	meta = Meta{
+		Title:    "Top discussed",
+		Link:     "https://example.com/top",
+		SelfLink: "https://example.com/v1/top.feed",
+		Updated:  time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC),
+	}
+	items = []Item{
+		{
+			Title:     "Go & generics <finally>",
+			Link:      "https://go.dev/blog/intro-generics",
+			Author:    "rsc",
+			Comments:  120,
+			Published: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			Title:    "Ask HN: Without url",
+			Comments: 7,
+		},
+	}
+)
+
+func TestWrite_Golden(t *testing.T) {
+	tests := []struct {
+		kind   Kind
+		golden string
+	}{
+		{kind: RSS, golden: "top.rss"},
+		{kind: Atom, golden: "top.atom"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(string(tt.kind), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, tt.kind, meta, items))
+
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+			}
+
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+// TestWrite_RSSSchema checks the rules of the RSS 2.0 specification.
+func TestWrite_RSSSchema(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, RSS, meta, items))
+
+	var doc struct {
+		XMLName xml.Name
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			// both rss link and atom:link of the self link
+			Links []struct {
+				XMLName xml.Name
+				Href    string `xml:"href,attr"`
+				Value   string `xml:",chardata"`
+			} `xml:"link"`
+			Description   string `xml:"description"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string `xml:"title"`
+				Description string `xml:"description"`
+				Link        string `xml:"link"`
+				PubDate     string `xml:"pubDate"`
+				GUID        struct {
+					IsPermaLink string `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, "rss", doc.XMLName.Local)
+	assert.Equal(t, "2.0", doc.Version)
+	// required channel elements
+	assert.NotEmpty(t, doc.Channel.Title)
+	require.Len(t, doc.Channel.Links, 2)
+	for _, l := range doc.Channel.Links {
+		if l.XMLName.Space == "" {
+			assertURL(t, l.Value)
+		} else {
+			assert.Equal(t, atomNamespace, l.XMLName.Space)
+			assertURL(t, l.Href)
+		}
+	}
+	assert.NotEmpty(t, doc.Channel.Description)
+	assertDate(t, time.RFC1123Z, doc.Channel.LastBuildDate)
+
+	require.Len(t, doc.Channel.Items, len(items))
+	for _, it := range doc.Channel.Items {
+		// at least one of title or description must be present
+		assert.True(t, it.Title != "" || it.Description != "")
+		if it.Link != "" {
+			assertURL(t, it.Link)
+		}
+		if it.PubDate != "" {
+			assertDate(t, time.RFC1123Z, it.PubDate)
+		}
+		assert.NotEmpty(t, it.GUID.Value)
+		if it.GUID.IsPermaLink == "true" {
+			assertURL(t, it.GUID.Value)
+		}
+	}
+}
+
+// TestWrite_AtomSchema checks the rules of RFC 4287.
+func TestWrite_AtomSchema(t *testing.T) {
+	// the golden file is checked, so the rules hold for what is committed
+	buf, err := os.ReadFile(filepath.Join("testdata", "top.atom"))
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, Atom, meta, items))
+	require.Equal(t, string(buf), out.String(), "golden file is outdated, run with -update")
+
+	type link struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	}
+	var doc struct {
+		XMLName xml.Name
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Links   []link `xml:"link"`
+		Author  []struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Links   []link `xml:"link"`
+			Content *struct {
+				Type string `xml:"type,attr"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(buf, &doc))
+
+	assert.Equal(t, xml.Name{Space: atomNamespace, Local: "feed"}, doc.XMLName)
+	assertURL(t, doc.ID)
+	assert.NotEmpty(t, doc.Title)
+	assertDate(t, time.RFC3339, doc.Updated)
+	require.Len(t, doc.Author, 1, "feed author is required when entries may have none")
+	assert.NotEmpty(t, doc.Author[0].Name)
+
+	rels := map[string]int{}
+	for _, l := range doc.Links {
+		rels[l.Rel]++
+		assertURL(t, l.Href)
+	}
+	assert.Equal(t, map[string]int{"alternate": 1, "self": 1}, rels)
+
+	require.Len(t, doc.Entries, len(items))
+	ids := map[string]struct{}{}
+	for _, e := range doc.Entries {
+		assertURL(t, e.ID)
+		assert.NotEmpty(t, e.Title)
+		assertDate(t, time.RFC3339, e.Updated)
+		assert.LessOrEqual(t, len(e.Links), 1, "one alternate link per entry")
+		if len(e.Links) == 0 {
+			require.NotNil(t, e.Content, "entry without alternate link must have content: %s", e.ID)
+			assert.Equal(t, "text", e.Content.Type)
+		}
+		ids[e.ID] = struct{}{}
+	}
+	assert.Len(t, ids, len(items), "entry ids must be unique")
+}
+
+func TestWrite_Defaults(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, RSS, Meta{}, nil))
+	assert.Contains(t, buf.String(), "<title>"+defaultTitle+"</title>")
+	assert.NotContains(t, buf.String(), "atom:link")
+
+	require.Error(t, Write(&buf, "json", Meta{}, nil))
+}
+
+func TestItem_id(t *testing.T) {
+	id, permaLink := items[0].id()
+	assert.True(t, permaLink)
+	assert.Equal(t, items[0].Link, id)
+
+	id, permaLink = items[1].id()
+	assert.False(t, permaLink)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+
+	again, _ := items[1].id()
+	assert.Equal(t, id, again, "id must be stable between runs")
+}
+
+func assertURL(t *testing.T, s string) {
+	t.Helper()
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	assert.NotEmpty(t, u.Scheme, s)
+}
+
+func assertDate(t *testing.T, layout, s string) {
+	t.Helper()
+	_, err := time.Parse(layout, s)
+	assert.NoError(t, err, s)
+}
@@ -0,0 +1,69 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// RSS 2.0: https://www.rssboard.org/rss-specification
+type (
+	rssDoc struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		Atom    string     `xml:"xmlns:atom,attr"`
+		DC      string     `xml:"xmlns:dc,attr"`
+		Channel rssChannel `xml:"channel"`
+	}
+	rssChannel struct {
+		Title         string    `xml:"title"`
+		Link          string    `xml:"link"`
+		Description   string    `xml:"description"`
+		LastBuildDate string    `xml:"lastBuildDate"`
+		Generator     string    `xml:"generator"`
+		SelfLink      *atomLink `xml:"atom:link,omitempty"`
+		Items         []rssItem `xml:"item"`
+	}
+	rssItem struct {
+		Title       string  `xml:"title"`
+		Link        string  `xml:"link,omitempty"`
+		Description string  `xml:"description"`
+		Creator     string  `xml:"dc:creator,omitempty"`
+		GUID        rssGUID `xml:"guid"`
+		PubDate     string  `xml:"pubDate,omitempty"`
+	}
+	rssGUID struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	}
+)
+
+func newRSS(meta Meta, items []Item) rssDoc {
+	ch := rssChannel{
+		Title:         meta.Title,
+		Link:          meta.Link,
+		Description:   meta.Description,
+		LastBuildDate: meta.Updated.Format(time.RFC1123Z),
+		Generator:     generator,
+		Items:         make([]rssItem, len(items)),
+	}
+	if meta.SelfLink != "" {
+		ch.SelfLink = &atomLink{Href: meta.SelfLink, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for i, it := range items {
+		id, permaLink := it.id()
+		ri := rssItem{
+			Title:       it.Title,
+			Link:        it.Link,
+			Description: it.description(),
+			Creator:     it.Author,
+			GUID:        rssGUID{IsPermaLink: permaLink, Value: id},
+		}
+		if !it.Published.IsZero() {
+			ri.PubDate = it.Published.UTC().Format(time.RFC1123Z)
+		}
+		ch.Items[i] = ri
+	}
+
+	return rssDoc{Version: "2.0", Atom: atomNamespace, DC: dcNamespace, Channel: ch}
+}
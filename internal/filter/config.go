@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"articles-service/internal/domains"
+	"articles-service/internal/window"
+)
+
+// Config describes the filter chain, zero value builds an empty chain.
+type Config struct {
+	Authors        []string
+	ExcludeAuthors []string
+	TitleRegex     string
+	MinComments    uint64
+	MaxComments    uint64
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	Window         window.Range
+	AllowDomains   []string
+	DenyDomains    []string
+	RequireURL     bool
+}
+
+// Build validates the config and returns the chain,
+// cheap filters go first to avoid redundant work(regex, url parsing).
+func (c Config) Build() (*Chain, error) {
+	if c.MaxComments != 0 && c.MinComments > c.MaxComments {
+		return nil, fmt.Errorf("min comments %d is greater than max comments %d", c.MinComments, c.MaxComments)
+	}
+	if !c.CreatedAfter.IsZero() && !c.CreatedBefore.IsZero() && !c.CreatedAfter.Before(c.CreatedBefore) {
+		return nil, errors.New("created after must be before created before")
+	}
+	if w := c.Window; !w.Since.IsZero() && !w.Until.IsZero() && !w.Since.Before(w.Until) {
+		return nil, errors.New("window since must be before until")
+	}
+
+	var filters []Filter
+	if c.RequireURL {
+		filters = append(filters, RequireURL())
+	}
+	if c.MinComments != 0 || c.MaxComments != 0 {
+		filters = append(filters, Comments(c.MinComments, c.MaxComments))
+	}
+	if !c.CreatedAfter.IsZero() || !c.CreatedBefore.IsZero() {
+		filters = append(filters, CreatedBetween(c.CreatedAfter, c.CreatedBefore))
+	}
+	if !c.Window.IsZero() {
+		filters = append(filters, Window(c.Window))
+	}
+	if len(c.Authors) != 0 {
+		filters = append(filters, AuthorInclude(c.Authors))
+	}
+	if len(c.ExcludeAuthors) != 0 {
+		filters = append(filters, AuthorExclude(c.ExcludeAuthors))
+	}
+	if c.TitleRegex != "" {
+		re, err := regexp.Compile(c.TitleRegex)
+		if err != nil {
+			return nil, fmt.Errorf("title regex: %w", err)
+		}
+		filters = append(filters, TitleRegex(re))
+	}
+	if len(c.AllowDomains) != 0 {
+		filters = append(filters, DomainAllow(normalizeDomains(c.AllowDomains)))
+	}
+	if len(c.DenyDomains) != 0 {
+		filters = append(filters, DomainDeny(normalizeDomains(c.DenyDomains)))
+	}
+
+	return NewChain(filters...), nil
+}
+
+// ParseTime accepts RFC3339 or a date(2006-01-02) in UTC.
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// SplitList splits a comma separated flag value skipping empty items.
+func SplitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func normalizeDomains(list []string) []string {
+	res := make([]string, 0, len(list))
+	for _, d := range list {
+		if h := domains.Host(d); h != "" {
+			res = append(res, h)
+		}
+	}
+	return res
+}
@@ -0,0 +1,176 @@
+package filter
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"articles-service/internal/domains"
+	"articles-service/internal/storage"
+	"articles-service/internal/window"
+)
+
+type (
+	// Filter decides whether the article goes further to the storage.
+	Filter interface {
+		Name() string
+		Accept(a storage.Article) bool
+	}
+	// Chain runs filters in order and counts rejections per filter,
+	// the article is counted only by the first filter that rejected it.
+	Chain struct {
+		filters  []Filter
+		rejected []atomic.Uint64
+	}
+	fn struct {
+		name   string
+		accept func(a storage.Article) bool
+	}
+)
+
+func NewChain(filters ...Filter) *Chain {
+	return &Chain{
+		filters:  filters,
+		rejected: make([]atomic.Uint64, len(filters)),
+	}
+}
+
+// Accept is safe for concurrent use, nil chain accepts everything.
+func (c *Chain) Accept(a storage.Article) bool {
+	if c == nil {
+		return true
+	}
+	for i, f := range c.filters {
+		if !f.Accept(a) {
+			c.rejected[i].Add(1)
+			return false
+		}
+	}
+	return true
+}
+
+// Rejected returns the number of rejected articles by filter name.
+func (c *Chain) Rejected() map[string]uint64 {
+	if c == nil {
+		return nil
+	}
+	res := make(map[string]uint64, len(c.filters))
+	for i, f := range c.filters {
+		res[f.Name()] += c.rejected[i].Load()
+	}
+	return res
+}
+
+// Reset zeroes the rejection counters before the next run.
+func (c *Chain) Reset() {
+	if c == nil {
+		return
+	}
+	for i := range c.rejected {
+		c.rejected[i].Store(0)
+	}
+}
+
+func (c *Chain) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.filters)
+}
+
+func (f fn) Name() string                  { return f.name }
+func (f fn) Accept(a storage.Article) bool { return f.accept(a) }
+
+// AuthorInclude accepts only articles of the given authors(case-insensitive).
+func AuthorInclude(authors []string) Filter {
+	set := lowerSet(authors)
+	return fn{name: "author_include", accept: func(a storage.Article) bool {
+		_, ok := set[strings.ToLower(a.Author)]
+		return ok
+	}}
+}
+
+// AuthorExclude drops articles of the given authors(case-insensitive).
+func AuthorExclude(authors []string) Filter {
+	set := lowerSet(authors)
+	return fn{name: "author_exclude", accept: func(a storage.Article) bool {
+		_, ok := set[strings.ToLower(a.Author)]
+		return !ok
+	}}
+}
+
+func TitleRegex(re *regexp.Regexp) Filter {
+	return fn{name: "title_regex", accept: func(a storage.Article) bool {
+		return re.MatchString(a.Name)
+	}}
+}
+
+// Comments accepts articles with min <= comments <= max, zero max means no upper bound.
+func Comments(min, max uint64) Filter {
+	return fn{name: "comments", accept: func(a storage.Article) bool {
+		return a.NumComments >= min && (max == 0 || a.NumComments <= max)
+	}}
+}
+
+// CreatedBetween accepts articles created in [from, to), zero bound is open.
+// Articles without created_at are rejected since we can't prove they are in range.
+func CreatedBetween(from, to time.Time) Filter {
+	return fn{name: "created_at", accept: func(a storage.Article) bool {
+		if a.CreatedAt.IsZero() {
+			return false
+		}
+		if !from.IsZero() && a.CreatedAt.Before(from) {
+			return false
+		}
+		if !to.IsZero() && !a.CreatedAt.Before(to) {
+			return false
+		}
+		return true
+	}}
+}
+
+// Window accepts articles created in the time window(top of the past 7 days etc.).
+func Window(r window.Range) Filter {
+	return fn{name: "window", accept: func(a storage.Article) bool {
+		return !a.CreatedAt.IsZero() && r.Contains(a.CreatedAt)
+	}}
+}
+
+// DomainAllow accepts only articles from the given domains or their subdomains.
+func DomainAllow(list []string) Filter {
+	return fn{name: "domain_allow", accept: func(a storage.Article) bool {
+		return matchDomain(domains.Host(a.URL), list)
+	}}
+}
+
+// DomainDeny drops articles from the given domains or their subdomains.
+func DomainDeny(list []string) Filter {
+	return fn{name: "domain_deny", accept: func(a storage.Article) bool {
+		return !matchDomain(domains.Host(a.URL), list)
+	}}
+}
+
+func RequireURL() Filter {
+	return fn{name: "require_url", accept: func(a storage.Article) bool {
+		return a.URL != ""
+	}}
+}
+
+func matchDomain(host string, list []string) bool {
+	if host == "" {
+		return false
+	}
+	return slices.ContainsFunc(list, func(d string) bool {
+		return host == d || strings.HasSuffix(host, "."+d)
+	})
+}
+
+func lowerSet(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		set[strings.ToLower(v)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,159 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/storage"
+	"articles-service/internal/window"
+)
+
+func TestConfig_Build(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	articles := []storage.Article{
+		{Name: "Go 1.22 released", NumComments: 100, Author: "rsc", URL: "https://go.dev/blog", CreatedAt: day(5)},
+		{Name: "Rust in the kernel", NumComments: 50, Author: "Linus", URL: "https://www.lwn.net/a", CreatedAt: day(10)},
+		{Name: "Ask HN: go or rust?", NumComments: 5, Author: "anon", CreatedAt: day(15)},
+		{Name: "Old news", NumComments: 10, Author: "anon", URL: "https://news.example.com/x"},
+	}
+
+	tests := []struct {
+		name         string
+		cfg          Config
+		wantAccepted []string
+		wantRejected map[string]uint64
+	}{
+		{
+			name:         "empty config accepts everything",
+			cfg:          Config{},
+			wantAccepted: []string{"Go 1.22 released", "Rust in the kernel", "Ask HN: go or rust?", "Old news"},
+			wantRejected: map[string]uint64{},
+		},
+		{
+			name:         "authors case-insensitive",
+			cfg:          Config{Authors: []string{"RSC", "linus"}},
+			wantAccepted: []string{"Go 1.22 released", "Rust in the kernel"},
+			wantRejected: map[string]uint64{"author_include": 2},
+		},
+		{
+			name:         "exclude authors",
+			cfg:          Config{ExcludeAuthors: []string{"anon"}},
+			wantAccepted: []string{"Go 1.22 released", "Rust in the kernel"},
+			wantRejected: map[string]uint64{"author_exclude": 2},
+		},
+		{
+			name:         "title regex",
+			cfg:          Config{TitleRegex: "(?i)rust"},
+			wantAccepted: []string{"Rust in the kernel", "Ask HN: go or rust?"},
+			wantRejected: map[string]uint64{"title_regex": 2},
+		},
+		{
+			name:         "comments range",
+			cfg:          Config{MinComments: 10, MaxComments: 50},
+			wantAccepted: []string{"Rust in the kernel", "Old news"},
+			wantRejected: map[string]uint64{"comments": 2},
+		},
+		{
+			name:         "created range drops unknown created_at",
+			cfg:          Config{CreatedAfter: day(5), CreatedBefore: day(15)},
+			wantAccepted: []string{"Go 1.22 released", "Rust in the kernel"},
+			wantRejected: map[string]uint64{"created_at": 2},
+		},
+		{
+			name:         "time window",
+			cfg:          Config{Window: window.Last(day(15), 7*24*time.Hour)},
+			wantAccepted: []string{"Rust in the kernel"},
+			wantRejected: map[string]uint64{"window": 3},
+		},
+		{
+			name:         "allow domains with subdomains",
+			cfg:          Config{AllowDomains: []string{"lwn.net", "example.com"}},
+			wantAccepted: []string{"Rust in the kernel", "Old news"},
+			wantRejected: map[string]uint64{"domain_allow": 2},
+		},
+		{
+			name:         "deny domains keeps articles without url",
+			cfg:          Config{DenyDomains: []string{"www.go.dev"}},
+			wantAccepted: []string{"Rust in the kernel", "Ask HN: go or rust?", "Old news"},
+			wantRejected: map[string]uint64{"domain_deny": 1},
+		},
+		{
+			name:         "first rejecting filter is counted",
+			cfg:          Config{RequireURL: true, MinComments: 20},
+			wantAccepted: []string{"Go 1.22 released", "Rust in the kernel"},
+			wantRejected: map[string]uint64{"require_url": 1, "comments": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := tt.cfg.Build()
+			require.NoError(t, err)
+
+			accepted := []string{}
+			for _, a := range articles {
+				if chain.Accept(a) {
+					accepted = append(accepted, a.Name)
+				}
+			}
+
+			assert.Equal(t, tt.wantAccepted, accepted)
+			assert.Equal(t, tt.wantRejected, chain.Rejected())
+		})
+	}
+}
+
+func TestConfig_Build_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "invalid regex", cfg: Config{TitleRegex: "("}},
+		{name: "min greater than max", cfg: Config{MinComments: 10, MaxComments: 5}},
+		{
+			name: "empty date range",
+			cfg: Config{
+				CreatedAfter:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.Build()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestChain_Nil(t *testing.T) {
+	var c *Chain
+	assert.True(t, c.Accept(storage.Article{}))
+	assert.Nil(t, c.Rejected())
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestParseTime(t *testing.T) {
+	got, err := ParseTime("2024-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), got)
+
+	got, err = ParseTime("2024-01-02T03:04:05Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), got)
+
+	_, err = ParseTime("yesterday")
+	require.Error(t, err)
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Nil(t, SplitList(""))
+	assert.Equal(t, []string{"a", "b"}, SplitList(" a, ,b,"))
+}
@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	JSON    Format = "json"
+	Console Format = "console"
+
+	// special outputs, anything else is a file
+	Stdout = "stdout"
+	Stderr = "stderr"
+
+	// sampling per message per second, the same as zap.NewProduction
+	sampleInitial    = 100
+	sampleThereafter = 100
+)
+
+type (
+	Format string
+	Config struct {
+		Level string
+		// controls the level when set, e.g. to change it on reload, Level is the initial one
+		AtomicLevel *zap.AtomicLevel
+		Format      Format
+		// stacktraces from warn, DPanic panics, caller and human readable time
+		Development bool
+		// log the first 100 entries with the same message per second and every 100th after
+		Sampling bool
+		// stdout, stderr or files, stderr by default
+		Outputs []string
+		Rotation
+	}
+	// Rotation of the file outputs, zero values are the lumberjack defaults.
+	Rotation struct {
+		// megabytes before the file is rotated
+		MaxSize int
+		// rotated files kept, 0 - all
+		MaxBackups int
+		// days rotated files are kept, 0 - forever
+		MaxAge   int
+		Compress bool
+	}
+)
+
+// isTerminal is replaced in tests.
+var isTerminal = isTerminalFile
+
+// isTerminalFile reports whether the file is a terminal rather than a file or a pipe.
+func isTerminalFile(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case JSON, Console:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q, expected one of: json, console", s)
+}
+
+// New builds the logger by the config, close flushes and closes the file outputs.
+func New(cfg Config) (logger *zap.Logger, close func() error, err error) {
+	level := zapcore.InfoLevel
+	if cfg.Level != "" {
+		if level, err = zapcore.ParseLevel(cfg.Level); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{Stderr}
+	}
+	var (
+		syncers []zapcore.WriteSyncer
+		closers []io.Closer
+		// colors only when every output is a terminal, never into files or pipes
+		color = true
+	)
+	for _, out := range outputs {
+		switch out {
+		case Stdout:
+			syncers = append(syncers, zapcore.Lock(os.Stdout))
+			color = color && isTerminal(os.Stdout)
+		case Stderr:
+			syncers = append(syncers, zapcore.Lock(os.Stderr))
+			color = color && isTerminal(os.Stderr)
+		default:
+			// lumberjack is safe for concurrent use
+			lj := &lumberjack.Logger{
+				Filename:   out,
+				MaxSize:    cfg.MaxSize,
+				MaxBackups: cfg.MaxBackups,
+				MaxAge:     cfg.MaxAge,
+				Compress:   cfg.Compress,
+			}
+			syncers = append(syncers, zapcore.AddSync(lj))
+			closers = append(closers, lj)
+			color = false
+		}
+	}
+
+	encCfg := zap.NewProductionEncoderConfig()
+	if cfg.Development {
+		encCfg = zap.NewDevelopmentEncoderConfig()
+	}
+	var enc zapcore.Encoder
+	switch cfg.Format {
+	case JSON, "":
+		enc = zapcore.NewJSONEncoder(encCfg)
+	case Console:
+		encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		if color {
+			encCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		enc = zapcore.NewConsoleEncoder(encCfg)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	var enabler zapcore.LevelEnabler = level
+	if cfg.AtomicLevel != nil {
+		cfg.AtomicLevel.SetLevel(level)
+		enabler = cfg.AtomicLevel
+	}
+	core := zapcore.NewCore(enc, zapcore.NewMultiWriteSyncer(syncers...), enabler)
+	if cfg.Sampling {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, sampleInitial, sampleThereafter)
+	}
+
+	opts := []zap.Option{zap.AddCaller(), zap.ErrorOutput(zapcore.Lock(os.Stderr))}
+	if cfg.Development {
+		opts = append(opts, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	} else {
+		opts = append(opts, zap.AddStacktrace(zapcore.ErrorLevel))
+	}
+	logger = zap.New(core, opts...)
+
+	return logger, func() error {
+		// sync of terminals fails on some platforms, files are written without buffering
+		_ = logger.Sync()
+		var err error
+		for _, c := range closers {
+			err = errors.Join(err, c.Close())
+		}
+		return err
+	}, nil
+}
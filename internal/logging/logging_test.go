@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"json", "console"} {
+		f, err := ParseFormat(s)
+		require.NoError(t, err)
+		assert.Equal(t, Format(s), f)
+	}
+
+	_, err := ParseFormat("logfmt")
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		assert func(t *testing.T, lines []string)
+	}{
+		{
+			name: "json with level",
+			cfg:  Config{Level: "warn", Format: JSON},
+			assert: func(t *testing.T, lines []string) {
+				require.Len(t, lines, 1)
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+				assert.Equal(t, "warn", entry["level"])
+				assert.Equal(t, "client", entry["logger"])
+				assert.Equal(t, "retrying page", entry["msg"])
+			},
+		},
+		{
+			name: "console into the file without colors",
+			cfg:  Config{Level: "debug", Format: Console},
+			assert: func(t *testing.T, lines []string) {
+				require.Len(t, lines, 3)
+				assert.Contains(t, lines[0], "DEBUG\tclient")
+				assert.Contains(t, lines[1], "INFO\tclient")
+				assert.NotContains(t, strings.Join(lines, ""), "\x1b[")
+			},
+		},
+		{
+			name: "sampling",
+			cfg:  Config{Level: "debug", Format: JSON, Sampling: true},
+			assert: func(t *testing.T, lines []string) {
+				// the first 100 of 150 same messages and the rest
+				require.Len(t, lines, 102)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			tt.cfg.Outputs = []string{path}
+			logger, closeLog, err := New(tt.cfg)
+			require.NoError(t, err)
+
+			l := logger.Named("client")
+			if tt.cfg.Sampling {
+				for i := 0; i < 150; i++ {
+					l.Debug("page fetched")
+				}
+			} else {
+				l.Debug("page fetched")
+			}
+			l.Info("external API")
+			l.Warn("retrying page")
+			require.NoError(t, closeLog())
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			tt.assert(t, strings.Split(strings.TrimSpace(string(data)), "\n"))
+		})
+	}
+}
+
+func TestNew_ConsoleColors(t *testing.T) {
+	// stderr redirected into a pipe like a log collector or a file
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stderr := os.Stderr
+	os.Stderr = w
+	t.Cleanup(func() { os.Stderr = stderr })
+
+	logPipe := func() string {
+		t.Helper()
+		logger, closeLog, err := New(Config{Format: Console})
+		require.NoError(t, err)
+		logger.Info("external API")
+		require.NoError(t, closeLog())
+		buf := make([]byte, 4096)
+		n, err := r.Read(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	out := logPipe()
+	assert.Contains(t, out, "INFO")
+	assert.NotContains(t, out, "\x1b[", "no colors when stderr is not a terminal")
+
+	isTerminal = func(*os.File) bool { return true }
+	t.Cleanup(func() { isTerminal = isTerminalFile })
+	assert.Contains(t, logPipe(), "\x1b[", "colors on terminals")
+}
+
+func TestNew_InvalidLevel(t *testing.T) {
+	_, _, err := New(Config{Level: "verbose"})
+	assert.Error(t, err)
+}
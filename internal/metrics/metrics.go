@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	namespace = "articles"
+
+	// channels of the pipeline
+	ChannelIn  = "in"
+	ChannelOut = "out"
+
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Metrics of the client, processor and storage, cumulative across runs.
+// Safe for concurrent use, nil metrics ignore everything like the nil report.Recorder.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests      *prometheus.CounterVec
+	retries       prometheus.Counter
+	limiterWait   prometheus.Histogram
+	pageLatency   prometheus.Histogram
+	pagesInFlight prometheus.Gauge
+	rowsProcessed prometheus.Counter
+	rowsDropped   *prometheus.CounterVec
+	channelDepth  *prometheus.GaugeVec
+	heapSize      prometheus.Gauge
+	heapEvictions prometheus.Counter
+	runs          *prometheus.CounterVec
+	runDuration   prometheus.Histogram
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "api", Name: "requests_total",
+			Help: "Requests to the articles api by response status, \"error\" for network errors.",
+		}, []string{"status"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "api", Name: "retries_total",
+			Help: "Retried page fetches.",
+		}),
+		limiterWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "api", Name: "limiter_wait_seconds",
+			Help:    "Time spent waiting for the rate limiter before a request.",
+			Buckets: []float64{.001, .01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}),
+		pageLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "api", Name: "page_latency_seconds",
+			Help:    "Latency of the page request including the body decoding.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		pagesInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "api", Name: "pages_in_flight",
+			Help: "Page requests in flight.",
+		}),
+		rowsProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rows_processed_total",
+			Help: "Rows seen by the processor.",
+		}),
+		rowsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rows_dropped_total",
+			Help: "Rows dropped by the processor by reason.",
+		}, []string{"reason"}),
+		channelDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "channel_depth",
+			Help: "Buffered messages of the pipeline channel sampled on receive.",
+		}, []string{"channel"}),
+		heapSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "heap_size",
+			Help: "Articles in the top storage heap.",
+		}),
+		heapEvictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "heap_evictions_total",
+			Help: "Articles evicted from the full top storage heap by a better one.",
+		}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "runs_total",
+			Help: "Finished crawls by result.",
+		}, []string{"result"}),
+		runDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "run_duration_seconds",
+			Help:    "Wall time of the crawl.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.retries, m.limiterWait, m.pageLatency, m.pagesInFlight,
+		m.rowsProcessed, m.rowsDropped, m.channelDepth,
+		m.heapSize, m.heapEvictions,
+		m.runs, m.runDuration,
+	)
+	// known labels are exported as zeros from the start
+	for _, ch := range []string{ChannelIn, ChannelOut} {
+		m.channelDepth.WithLabelValues(ch)
+	}
+	for _, result := range []string{"success", "failure"} {
+		m.runs.WithLabelValues(result)
+	}
+
+	return m
+}
+
+// Handler serves metrics in the text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Request counts the response status, 0 means a network error.
+func (m *Metrics) Request(status int) {
+	if m == nil {
+		return
+	}
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	m.requests.WithLabelValues(label).Inc()
+}
+
+func (m *Metrics) Retry() {
+	if m == nil {
+		return
+	}
+	m.retries.Inc()
+}
+
+func (m *Metrics) LimiterWait(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.limiterWait.Observe(d.Seconds())
+}
+
+func (m *Metrics) PageLatency(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.pageLatency.Observe(d.Seconds())
+}
+
+// PageStarted marks the page request in flight until the returned func is called.
+func (m *Metrics) PageStarted() (done func()) {
+	if m == nil {
+		return func() {}
+	}
+	m.pagesInFlight.Inc()
+	return m.pagesInFlight.Dec
+}
+
+func (m *Metrics) RowProcessed() {
+	if m == nil {
+		return
+	}
+	m.rowsProcessed.Inc()
+}
+
+func (m *Metrics) RowDropped(reason string) {
+	if m == nil {
+		return
+	}
+	m.rowsDropped.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) ChannelDepth(channel string, depth int) {
+	if m == nil {
+		return
+	}
+	m.channelDepth.WithLabelValues(channel).Set(float64(depth))
+}
+
+func (m *Metrics) HeapSize(n int) {
+	if m == nil {
+		return
+	}
+	m.heapSize.Set(float64(n))
+}
+
+func (m *Metrics) HeapEviction() {
+	if m == nil {
+		return
+	}
+	m.heapEvictions.Inc()
+}
+
+// RunFinished counts the crawl and its wall time.
+func (m *Metrics) RunFinished(d time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if failed {
+		result = "failure"
+	}
+	m.runs.WithLabelValues(result).Inc()
+	m.runDuration.Observe(d.Seconds())
+}
+
+// Serve serves /metrics on the address until ctx is canceled.
+func Serve(ctx context.Context, logger *zap.Logger, addr string, m *Metrics) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("metrics server listening", zap.String("addr", addr))
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("metrics server: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("metrics server shutdown: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	logger.Info("metrics server gracefully stopped")
+
+	return nil
+}
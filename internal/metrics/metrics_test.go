@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.Request(200)
+	m.Request(200)
+	m.Request(503)
+	m.Request(0)
+	m.Retry()
+	m.LimiterWait(20 * time.Millisecond)
+	m.PageLatency(150 * time.Millisecond)
+	done := m.PageStarted()
+	m.PageStarted()
+	done()
+	m.RowProcessed()
+	m.RowDropped("filtered")
+	m.ChannelDepth(ChannelOut, 3)
+	m.HeapSize(10)
+	m.HeapEviction()
+	m.RunFinished(2*time.Second, false)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`articles_api_requests_total{status="200"} 2`,
+		`articles_api_requests_total{status="503"} 1`,
+		`articles_api_requests_total{status="error"} 1`,
+		`articles_api_retries_total 1`,
+		`articles_api_limiter_wait_seconds_count 1`,
+		`articles_api_page_latency_seconds_bucket{le="0.25"} 1`,
+		`articles_api_pages_in_flight 1`,
+		`articles_rows_processed_total 1`,
+		`articles_rows_dropped_total{reason="filtered"} 1`,
+		`articles_channel_depth{channel="in"} 0`,
+		`articles_channel_depth{channel="out"} 3`,
+		`articles_heap_size 10`,
+		`articles_heap_evictions_total 1`,
+		`articles_runs_total{result="failure"} 0`,
+		`articles_runs_total{result="success"} 1`,
+		`articles_run_duration_seconds_count 1`,
+		`go_goroutines`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+	assert.NotPanics(t, func() {
+		m.Request(200)
+		m.Retry()
+		m.LimiterWait(time.Second)
+		m.PageLatency(time.Second)
+		m.PageStarted()()
+		m.RowProcessed()
+		m.RowDropped("filtered")
+		m.ChannelDepth(ChannelIn, 1)
+		m.HeapSize(1)
+		m.HeapEviction()
+		m.RunFinished(time.Second, true)
+	})
+}
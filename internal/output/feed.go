@@ -0,0 +1,46 @@
+package output
+
+import (
+	"io"
+
+	"articles-service/internal/feed"
+)
+
+const (
+	RSS  Format = "rss"
+	Atom Format = "atom"
+)
+
+// FeedWriter renders the top list as RSS 2.0 or Atom 1.0 feed.
+type FeedWriter struct {
+	kind feed.Kind
+	meta feed.Meta
+}
+
+func NewFeed(f Format, meta feed.Meta) *FeedWriter {
+	return &FeedWriter{kind: feed.Kind(f), meta: meta}
+}
+
+func (f *FeedWriter) Write(w io.Writer, records []Record) error {
+	return feed.Write(w, f.kind, f.meta, FeedItems(records))
+}
+
+// FeedItems converts records into feed items, domain records have no articles to subscribe to.
+func FeedItems(records []Record) []feed.Item {
+	items := make([]feed.Item, 0, len(records))
+	for _, r := range records {
+		if r.Title == "" {
+			continue
+		}
+		items = append(items, feed.Item{
+			Title:     r.Title,
+			Link:      r.URL,
+			Author:    r.Author,
+			Comments:  r.Comments,
+			Published: r.CreatedAt,
+		})
+	}
+	return items
+}
+
+func (f Format) IsFeed() bool { return f == RSS || f == Atom }
@@ -0,0 +1,315 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"articles-service/internal/feed"
+)
+
+const (
+	Text     Format = "text"
+	JSON     Format = "json"
+	NDJSON   Format = "ndjson"
+	CSV      Format = "csv"
+	TSV      Format = "tsv"
+	Markdown Format = "markdown"
+	Table    Format = "table"
+)
+
+type (
+	Format string
+	// Record is one row of the top list, optional fields are empty
+	// when upstream has no data or the mode doesn't have them.
+	Record struct {
+		Rank int `json:"rank"`
+		// Window the record belongs to(top per time window)
+		Window string `json:"window,omitempty"`
+		// Domain of the domain leaderboard, Title is empty then
+		Domain    string    `json:"domain,omitempty"`
+		Title     string    `json:"title,omitempty"`
+		Comments  uint64    `json:"comments"`
+		Articles  uint64    `json:"articles,omitempty"`
+		Author    string    `json:"author,omitempty"`
+		URL       string    `json:"url,omitempty"`
+		CreatedAt time.Time `json:"created_at,omitzero"`
+	}
+	// Writer writes records in one format.
+	Writer interface {
+		Write(w io.Writer, records []Record) error
+	}
+	WriterFunc func(w io.Writer, records []Record) error
+
+	column struct {
+		name  string
+		value func(r Record) string
+	}
+)
+
+var writers = map[Format]Writer{
+	Text:     WriterFunc(writeText),
+	JSON:     WriterFunc(writeJSON),
+	NDJSON:   WriterFunc(writeNDJSON),
+	CSV:      WriterFunc(func(w io.Writer, records []Record) error { return writeDelimited(w, records, ',') }),
+	TSV:      WriterFunc(func(w io.Writer, records []Record) error { return writeDelimited(w, records, '\t') }),
+	Markdown: WriterFunc(writeMarkdown),
+	Table:    WriterFunc(writeTable),
+	RSS:      NewFeed(RSS, feed.Meta{}),
+	Atom:     NewFeed(Atom, feed.Meta{}),
+}
+
+func (f WriterFunc) Write(w io.Writer, records []Record) error { return f(w, records) }
+
+func ParseFormat(s string) (Format, error) {
+	if _, ok := writers[Format(s)]; !ok {
+		return "", fmt.Errorf("unknown format %q, expected one of: %s", s, strings.Join(Formats(), ", "))
+	}
+	return Format(s), nil
+}
+
+// Formats returns the names of supported formats.
+func Formats() []string {
+	return []string{
+		string(Text), string(JSON), string(NDJSON), string(CSV), string(TSV),
+		string(Markdown), string(Table), string(RSS), string(Atom),
+	}
+}
+
+// ContentType returns the media type of the format.
+func ContentType(f Format) string {
+	switch f {
+	case JSON:
+		return "application/json"
+	case NDJSON:
+		return "application/x-ndjson"
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case TSV:
+		return "text/tab-separated-values; charset=utf-8"
+	case Markdown:
+		return "text/markdown; charset=utf-8"
+	case RSS, Atom:
+		return feed.ContentType(feed.Kind(f))
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+func NewWriter(f Format) (Writer, error) {
+	w, ok := writers[f]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+	return w, nil
+}
+
+// WriteFile writes to stdout when path is empty or "-", otherwise atomically
+// replaces the file(temp file + rename) so readers never see a partial result.
+func WriteFile(path string, write func(w io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	// no-op after successful rename
+	defer os.Remove(tmp.Name())
+
+	if err = write(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync %s: %w", tmp.Name(), err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", tmp.Name(), err)
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// writeText keeps the original one title per line output,
+// domains, authors and stories are written with their totals and windows are separated by "# since .. until" header.
+func writeText(w io.Writer, records []Record) error {
+	var window string
+	for _, r := range records {
+		if r.Window != "" && r.Window != window {
+			window = r.Window
+			if _, err := fmt.Fprintf(w, "# %s\n", window); err != nil {
+				return err
+			}
+		}
+
+		line := r.Title
+		switch {
+		case r.Domain != "":
+			line = fmt.Sprintf("%s\t%d\t%d", r.Domain, r.Comments, r.Articles)
+		case r.Title == "" && r.Author != "":
+			line = fmt.Sprintf("%s\t%d\t%d", r.Author, r.Comments, r.Articles)
+		// stories
+		case r.Articles > 0:
+			line = fmt.Sprintf("%s\t%d\t%d", r.Title, r.Comments, r.Articles)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, records []Record) error {
+	if records == nil {
+		records = []Record{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func writeNDJSON(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDelimited(w io.Writer, records []Record, delimiter rune) error {
+	cols := columns(records)
+
+	cw := csv.NewWriter(w)
+	cw.Comma = delimiter
+	if err := cw.Write(header(cols)); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := cw.Write(row(cols, r)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func writeMarkdown(w io.Writer, records []Record) error {
+	cols := columns(records)
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	lines := make([]string, 0, len(records)+2)
+	lines = append(lines, "| "+strings.Join(header(cols), " | ")+" |")
+	lines = append(lines, "|"+strings.Repeat("---|", len(cols)))
+	for _, r := range records {
+		cells := row(cols, r)
+		for i := range cells {
+			cells[i] = escape.Replace(cells[i])
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func writeTable(w io.Writer, records []Record) error {
+	cols := columns(records)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, strings.ToUpper(strings.Join(header(cols), "\t"))); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if _, err := fmt.Fprintln(tw, strings.Join(row(cols, r), "\t")); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// columns returns the columns of the tabular formats,
+// optional ones are included only if any record has them.
+func columns(records []Record) []column {
+	has := func(value func(r Record) string) bool {
+		for _, r := range records {
+			if value(r) != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	all := []struct {
+		column
+		optional bool
+	}{
+		{column: column{"rank", func(r Record) string { return strconv.Itoa(r.Rank) }}},
+		{column: column{"window", func(r Record) string { return r.Window }}, optional: true},
+		{column: column{"domain", func(r Record) string { return r.Domain }}, optional: true},
+		{column: column{"title", func(r Record) string { return r.Title }}, optional: true},
+		{column: column{"comments", func(r Record) string { return strconv.FormatUint(r.Comments, 10) }}},
+		{column: column{"articles", func(r Record) string {
+			if r.Articles == 0 {
+				return ""
+			}
+			return strconv.FormatUint(r.Articles, 10)
+		}}, optional: true},
+		{column: column{"author", func(r Record) string { return r.Author }}, optional: true},
+		{column: column{"url", func(r Record) string { return r.URL }}, optional: true},
+		{column: column{"created_at", func(r Record) string {
+			if r.CreatedAt.IsZero() {
+				return ""
+			}
+			return r.CreatedAt.UTC().Format(time.RFC3339)
+		}}, optional: true},
+	}
+
+	cols := make([]column, 0, len(all))
+	for _, c := range all {
+		if !c.optional || has(c.value) {
+			cols = append(cols, c.column)
+		}
+	}
+	// title is the main column of the top list even if upstream returned nothing
+	if len(records) == 0 {
+		cols = []column{all[0].column, all[3].column, all[4].column}
+	}
+
+	return cols
+}
+
+func header(cols []column) []string {
+	res := make([]string, len(cols))
+	for i, c := range cols {
+		res[i] = c.name
+	}
+	return res
+}
+
+func row(cols []column, r Record) []string {
+	res := make([]string, len(cols))
+	for i, c := range cols {
+		res[i] = c.value(r)
+	}
+	return res
+}
@@ -0,0 +1,177 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var records = []Record{
+	{Rank: 1, Title: "Go, generics", Comments: 10, Author: "rsc", URL: "https://go.dev", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	{Rank: 2, Title: "Ask | HN", Comments: 5},
+}
+
+func TestWriters(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Text,
+			want:   "Go, generics\nAsk | HN\n",
+		},
+		{
+			format: JSON,
+			want: `[
+  {
+    "rank": 1,
+    "title": "Go, generics",
+    "comments": 10,
+    "author": "rsc",
+    "url": "https://go.dev",
+    "created_at": "2024-01-02T03:04:05Z"
+  },
+  {
+    "rank": 2,
+    "title": "Ask | HN",
+    "comments": 5
+  }
+]
+`,
+		},
+		{
+			format: NDJSON,
+			want: `{"rank":1,"title":"Go, generics","comments":10,"author":"rsc","url":"https://go.dev","created_at":"2024-01-02T03:04:05Z"}
+{"rank":2,"title":"Ask | HN","comments":5}
+`,
+		},
+		{
+			format: CSV,
+			want: `rank,title,comments,author,url,created_at
+1,"Go, generics",10,rsc,https://go.dev,2024-01-02T03:04:05Z
+2,Ask | HN,5,,,
+`,
+		},
+		{
+			format: TSV,
+			want: "rank\ttitle\tcomments\tauthor\turl\tcreated_at\n" +
+				"1\tGo, generics\t10\trsc\thttps://go.dev\t2024-01-02T03:04:05Z\n" +
+				"2\tAsk | HN\t5\t\t\t\n",
+		},
+		{
+			format: Markdown,
+			want: `| rank | title | comments | author | url | created_at |
+|---|---|---|---|---|---|
+| 1 | Go, generics | 10 | rsc | https://go.dev | 2024-01-02T03:04:05Z |
+| 2 | Ask \| HN | 5 |  |  |  |
+`,
+		},
+		{
+			format: Table,
+			want: `RANK  TITLE         COMMENTS  AUTHOR  URL             CREATED_AT
+1     Go, generics  10        rsc     https://go.dev  2024-01-02T03:04:05Z
+2     Ask | HN      5                                 
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(string(tt.format), func(t *testing.T) {
+			w, err := NewWriter(tt.format)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, w.Write(&buf, records))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWriters_DomainsAndWindows(t *testing.T) {
+	domains := []Record{
+		{Rank: 1, Domain: "go.dev", Comments: 10, Articles: 2},
+	}
+	windows := []Record{
+		{Rank: 1, Window: "w1", Title: "a", Comments: 3},
+		{Rank: 2, Window: "w1", Title: "b", Comments: 2},
+		{Rank: 1, Window: "w2", Title: "c", Comments: 1},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeText(&buf, domains))
+	assert.Equal(t, "go.dev\t10\t2\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeDelimited(&buf, domains, ','))
+	assert.Equal(t, "rank,domain,comments,articles\n1,go.dev,10,2\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeText(&buf, windows))
+	assert.Equal(t, "# w1\na\nb\n# w2\nc\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeDelimited(&buf, windows, ','))
+	assert.Equal(t, "rank,window,title,comments\n1,w1,a,3\n2,w1,b,2\n1,w2,c,1\n", buf.String())
+}
+
+func TestWriters_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeJSON(&buf, nil))
+	assert.Equal(t, "[]\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeDelimited(&buf, nil, ','))
+	assert.Equal(t, "rank,title,comments\n", buf.String())
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats() {
+		got, err := ParseFormat(f)
+		require.NoError(t, err)
+		assert.Equal(t, Format(f), got)
+	}
+
+	_, err := ParseFormat("xml")
+	require.Error(t, err)
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "top.txt")
+
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+
+	err := WriteFile(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	t.Run("failed write keeps the old file and removes temp", func(t *testing.T) {
+		err := WriteFile(path, func(w io.Writer) error {
+			_, _ = io.WriteString(w, "partial")
+			return errors.New("boom")
+		})
+		require.Error(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
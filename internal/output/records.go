@@ -0,0 +1,73 @@
+package output
+
+import "articles-service/internal/storage"
+
+// Articles converts sorted top articles into records ranked from 1.
+func Articles(articles []storage.Article) []Record {
+	records := make([]Record, len(articles))
+	for i, a := range articles {
+		records[i] = Record{
+			Rank:      i + 1,
+			Title:     a.Name,
+			Comments:  a.NumComments,
+			Author:    a.Author,
+			URL:       a.URL,
+			CreatedAt: a.CreatedAt,
+		}
+	}
+	return records
+}
+
+// Groups converts the sorted domain leaderboard into records ranked from 1.
+func Groups(groups []storage.Group) []Record {
+	records := make([]Record, len(groups))
+	for i, g := range groups {
+		records[i] = Record{
+			Rank:     i + 1,
+			Domain:   g.Key,
+			Comments: g.NumComments,
+			Articles: g.NumArticles,
+		}
+	}
+	return records
+}
+
+// Authors converts the sorted authors leaderboard into records ranked from 1.
+func Authors(groups []storage.Group) []Record {
+	records := make([]Record, len(groups))
+	for i, g := range groups {
+		records[i] = Record{
+			Rank:     i + 1,
+			Author:   g.Key,
+			Comments: g.NumComments,
+			Articles: g.NumArticles,
+		}
+	}
+	return records
+}
+
+// Stories converts the sorted stories leaderboard into records ranked from 1.
+func Stories(groups []storage.Group) []Record {
+	records := make([]Record, len(groups))
+	for i, g := range groups {
+		records[i] = Record{
+			Rank:     i + 1,
+			Title:    g.Key,
+			Comments: g.NumComments,
+			Articles: g.NumArticles,
+		}
+	}
+	return records
+}
+
+// Windows converts top per time window into records ranked from 1 inside each window.
+func Windows(windows []storage.WindowTop) []Record {
+	var records []Record
+	for _, w := range windows {
+		for _, r := range Articles(w.Articles) {
+			r.Window = w.Window.String()
+			records = append(records, r)
+		}
+	}
+	return records
+}
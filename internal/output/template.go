@@ -0,0 +1,106 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"articles-service/internal/report"
+)
+
+type (
+	// TemplateWriter renders records with a user defined text/template.
+	TemplateWriter struct {
+		tmpl    *template.Template
+		summary func() report.Report
+	}
+	// TemplateData is the root object of the template.
+	TemplateData struct {
+		Records     []Record
+		Summary     report.Report
+		GeneratedAt time.Time
+	}
+)
+
+// TemplateFuncs are the helpers available in templates in addition to the builtin ones.
+var TemplateFuncs = template.FuncMap{
+	"truncate":   truncate,
+	"date":       date,
+	"urlescape":  url.QueryEscape,
+	"pathescape": url.PathEscape,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"join":       strings.Join,
+	"add":        func(a, b int) int { return a + b },
+}
+
+// NewTemplate parses the template, value prefixed with "@" is the path of the template file.
+// summary is called on every write to get the report of the finished run, may be nil.
+func NewTemplate(value string, summary func() report.Report) (*TemplateWriter, error) {
+	name, text := "inline", value
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+		name, text = path, string(data)
+	}
+
+	tmpl, err := template.New(name).Funcs(TemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	return &TemplateWriter{tmpl: tmpl, summary: summary}, nil
+}
+
+func (t *TemplateWriter) Write(w io.Writer, records []Record) error {
+	data := TemplateData{
+		Records:     records,
+		GeneratedAt: time.Now().UTC(),
+	}
+	if t.summary != nil {
+		data.Summary = t.summary()
+	}
+
+	if err := t.tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+	return nil
+}
+
+// truncate shortens s to n runes adding "…", usage: {{ truncate 50 .Title }}.
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if n == 1 {
+		return "…"
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// date formats t with the layout or a named one(rfc3339, date, datetime, rfc1123z),
+// zero time is rendered as empty string, usage: {{ date "date" .CreatedAt }}.
+func date(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	switch strings.ToLower(layout) {
+	case "rfc3339":
+		layout = time.RFC3339
+	case "date":
+		layout = time.DateOnly
+	case "datetime":
+		layout = time.DateTime
+	case "rfc1123z":
+		layout = time.RFC1123Z
+	}
+	return t.UTC().Format(layout)
+}
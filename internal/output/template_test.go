@@ -0,0 +1,95 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/report"
+)
+
+func TestTemplateWriter(t *testing.T) {
+	summary := func() report.Report {
+		return report.Report{Pages: report.Pages{Fetched: 3}}
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "fields and summary",
+			template: `{{range .Records}}{{.Rank}}. {{.Title}} ({{.Comments}}) by {{.Author}}{{"\n"}}{{end}}pages: {{.Summary.Pages.Fetched}}`,
+			want:     "1. Go, generics (10) by rsc\n2. Ask | HN (5) by \npages: 3",
+		},
+		{
+			name:     "helpers",
+			template: `{{range .Records}}{{truncate 5 .Title}}|{{date "date" .CreatedAt}}|{{urlescape .Title}}|{{upper .Author}};{{end}}`,
+			want:     "Go, …|2024-01-02|Go%2C+generics|RSC;Ask …||Ask+%7C+HN|;",
+		},
+		{
+			name:     "slack link",
+			template: `{{with index .Records 0}}<{{.URL}}|{{.Title}}>{{end}}`,
+			want:     "<https://go.dev|Go, generics>",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewTemplate(tt.template, summary)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, w.Write(&buf, records))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestNewTemplate_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digest.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{{len .Records}}`), 0o644))
+
+	w, err := NewTemplate("@"+path, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf, records))
+	assert.Equal(t, "2", buf.String())
+
+	_, err = NewTemplate("@"+filepath.Join(t.TempDir(), "missing"), nil)
+	require.Error(t, err)
+}
+
+func TestNewTemplate_Errors(t *testing.T) {
+	_, err := NewTemplate(`{{.Records`, nil)
+	require.Error(t, err, "parse error")
+
+	w, err := NewTemplate(`{{.Unknown}}`, nil)
+	require.NoError(t, err)
+	require.Error(t, w.Write(&bytes.Buffer{}, records), "execute error")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "hello", truncate(5, "hello"))
+	assert.Equal(t, "hel…", truncate(4, "hello"))
+	assert.Equal(t, "…", truncate(1, "hello"))
+	assert.Equal(t, "hello", truncate(0, "hello"))
+	assert.Equal(t, "при…", truncate(4, "привет"))
+}
+
+func TestDate(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	assert.Equal(t, "2024-01-02T03:04:05Z", date("rfc3339", ts))
+	assert.Equal(t, "2024-01-02 03:04:05", date("datetime", ts))
+	assert.Equal(t, "02.01.2024", date("02.01.2006", ts))
+	assert.Equal(t, "", date("date", time.Time{}))
+}
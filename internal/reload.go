@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"articles-service/internal/filter"
+	"articles-service/internal/output"
+)
+
+// settings applied by the reload, others need the restart
+var reloadable = map[string]bool{
+	"limit": true, "rps": true, "burst": true, "log-level": true, "timeout": true,
+	// filters
+	"author": true, "exclude-author": true, "title-regex": true, "min-comments": true, "max-comments": true,
+	"created-after": true, "created-before": true, "allow-domain": true, "deny-domain": true, "require-url": true,
+	"last": true, "ref-time": true, "since": true, "until": true,
+	// output targets
+	"output": true, "format": true, "template": true, "feed-title": true, "feed-link": true, "summary": true,
+}
+
+// reloaded is the validated config waiting for the next run.
+type reloaded struct {
+	args   args
+	chain  *filter.Chain
+	writer output.Writer
+}
+
+// reload rereads the config file and env with the original flags(SIGHUP in watch/serve modes).
+// The rate limit and the log level are applied right away, the rest before the next run.
+// The invalid config is rejected keeping the current one.
+func (a *App) reload() {
+	a.logger.Info("reloading the config")
+
+	r, level, err := a.loadReloaded()
+	if err != nil {
+		a.logger.Error("config reload rejected, keeping the current config", zap.Error(err))
+		return
+	}
+
+	a.proc.SetRateLimit(r.args.api.RPS, r.args.api.Burst)
+	if a.level != nil {
+		a.level.SetLevel(level)
+	}
+	a.mu.Lock()
+	a.reloaded = r
+	a.mu.Unlock()
+
+	var restart []string
+	for k, v := range r.args.settings {
+		if !reloadable[k] && a.args.settings[k] != v {
+			restart = append(restart, k)
+		}
+	}
+	slices.Sort(restart)
+	if len(restart) > 0 {
+		a.logger.Warn("changed settings are not reloadable, restart to apply", zap.Strings("settings", restart))
+	}
+	a.logger.Info("config reloaded, applied from the next run")
+}
+
+// loadReloaded parses and builds everything of the reloaded config which could fail.
+func (a *App) loadReloaded() (*reloaded, zapcore.Level, error) {
+	next, err := parseCommandArgs(a.args.command, a.argv)
+	if err != nil {
+		return nil, 0, err
+	}
+	level, err := zapcore.ParseLevel(next.log.Level)
+	if err != nil {
+		return nil, 0, err
+	}
+	chain, err := next.filter.Build()
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid filters: %w", err)
+	}
+	writer, err := a.newWriter(next)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &reloaded{args: next, chain: chain, writer: writer}, level, nil
+}
+
+// applyReloaded applies the reloaded config before the run, called by the scheduler only.
+func (a *App) applyReloaded() {
+	a.mu.Lock()
+	r := a.reloaded
+	a.reloaded = nil
+	a.mu.Unlock()
+	if r == nil {
+		return
+	}
+
+	next := r.args
+	a.args.limit, a.args.timeout = next.limit, next.timeout
+	a.args.filter = next.filter
+	a.args.output, a.args.format, a.args.template, a.args.feed = next.output, next.format, next.template, next.feed
+	a.args.summary = next.summary
+	a.args.api.RPS, a.args.api.Burst = next.api.RPS, next.api.Burst
+	a.args.log.Level = next.log.Level
+
+	params := make(map[string]string, len(next.params))
+	for k, v := range a.args.params {
+		if !reloadable[settingKey(k)] {
+			params[k] = v
+		}
+	}
+	for k, v := range next.params {
+		if reloadable[settingKey(k)] {
+			params[k] = v
+		}
+	}
+	a.args.params = params
+
+	a.filter, a.writer = r.chain, r.writer
+	a.proc.SetFilter(r.chain)
+	a.proc.SetLimit(next.limit)
+}
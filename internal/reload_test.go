@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"articles-service/internal/output"
+)
+
+func TestApp_Reload(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "limit: 5\nformat: text\nlog-level: info\n")
+	argv := []string{"-watch", "-config=" + path, "-addr=:9000"}
+	args, err := parseArgs(argv)
+	require.NoError(t, err)
+	app, err := newApp(zap.NewNop(), args)
+	require.NoError(t, err)
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	app.argv, app.level = argv, &level
+
+	// invalid config is rejected
+	require.NoError(t, os.WriteFile(path, []byte("limit: 500\n"), 0o644))
+	app.reload()
+	app.applyReloaded()
+	assert.Equal(t, 5, app.args.limit)
+
+	require.NoError(t, os.WriteFile(path, []byte("limit: 7\nformat: json\nlog-level: debug\nauthor: [alice]\ninterval: 1m\n"), 0o644))
+	app.reload()
+	// applied right away
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+	// applied before the next run
+	assert.Equal(t, 5, app.args.limit)
+	app.applyReloaded()
+	assert.Equal(t, 7, app.args.limit)
+	assert.Equal(t, output.JSON, app.args.format)
+	assert.Equal(t, []string{"alice"}, app.args.filter.Authors)
+	assert.Equal(t, "7", app.args.params["l"])
+	// not reloadable
+	assert.Equal(t, ":9000", app.args.addr)
+	assert.Equal(t, args.interval, app.args.interval)
+}
+
+func TestApp_Reload_RateLimit(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "limit: 5\nrps: 4\nburst: 2\n")
+	argv := []string{"-watch", "-config=" + path}
+	args, err := parseArgs(argv)
+	require.NoError(t, err)
+	app, err := newApp(zap.NewNop(), args)
+	require.NoError(t, err)
+	app.argv = argv
+
+	for _, cfg := range []string{"limit: 5\nrps: 4\nburst: 0\n", "limit: 5\nrps: 0\nburst: 2\n"} {
+		require.NoError(t, os.WriteFile(path, []byte(cfg), 0o644))
+		app.reload()
+		app.applyReloaded()
+		assert.Equal(t, 4.0, app.args.api.RPS, cfg)
+		assert.Equal(t, 2, app.args.api.Burst, cfg)
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("limit: 5\nrps: 8\nburst: 3\n"), 0o644))
+	app.reload()
+	app.applyReloaded()
+	assert.Equal(t, 3, app.args.api.Burst)
+}
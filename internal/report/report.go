@@ -0,0 +1,293 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// reasons of dropped rows
+const (
+	DropNilTitle       DropReason = "nil_title"
+	DropNilNumComments DropReason = "nil_num_comments"
+	DropFiltered       DropReason = "filtered"
+	DropDeduped        DropReason = "deduped"
+)
+
+type (
+	// Recorder collects run statistics from the client and the processor,
+	// safe for concurrent use, nil recorder ignores everything.
+	Recorder struct {
+		mu          sync.Mutex
+		startedAt   time.Time
+		finishedAt  time.Time
+		totalPages  int
+		totalRows   int
+		requests    int
+		fetched     int
+		retried     int
+		cacheHits   int
+		reused      int
+		failedPages []int
+		interrupted int
+		latencies   []time.Duration
+		rowsSeen    int
+		dropped     map[DropReason]int
+	}
+	DropReason string
+
+	// Report is the structured summary of the run.
+	Report struct {
+		StartedAt    time.Time `json:"started_at"`
+		FinishedAt   time.Time `json:"finished_at"`
+		WallTimeMs   int64     `json:"wall_time_ms"`
+		EffectiveRPS float64   `json:"effective_rps"`
+		Pages        Pages     `json:"pages"`
+		Rows         Rows      `json:"rows"`
+		LatencyMs    Latency   `json:"page_latency_ms"`
+	}
+	Pages struct {
+		Total    int `json:"total"`
+		Requests int `json:"requests"`
+		Fetched  int `json:"fetched"`
+		Failed   int `json:"failed"`
+		// stopped by the signal or the deadline, not failed by upstream
+		Interrupted int `json:"interrupted"`
+		Retried     int `json:"retried"`
+		CacheHits   int `json:"cache_hits"`
+		// taken from the previous run without requests(incremental crawl)
+		Reused      int   `json:"reused"`
+		FailedPages []int `json:"failed_pages,omitempty"`
+	}
+	Rows struct {
+		// Total is reported by upstream
+		Total      int                `json:"total"`
+		Seen       int                `json:"seen"`
+		Accepted   int                `json:"accepted"`
+		Dropped    map[DropReason]int `json:"dropped"`
+		FilteredBy map[string]uint64  `json:"filtered_by,omitempty"`
+	}
+	Latency struct {
+		P50 float64 `json:"p50"`
+		P90 float64 `json:"p90"`
+		P99 float64 `json:"p99"`
+		Max float64 `json:"max"`
+	}
+)
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		startedAt: time.Now(),
+		dropped:   make(map[DropReason]int),
+	}
+}
+
+// SetTotals stores upstream totals from the first page.
+func (r *Recorder) SetTotals(pages, rows int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.totalPages, r.totalRows = pages, rows
+}
+
+// Request counts every http request to upstream including retries.
+func (r *Recorder) Request() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+}
+
+func (r *Recorder) PageFetched(latency time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetched++
+	r.latencies = append(r.latencies, latency)
+}
+
+func (r *Recorder) PageRetried() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retried++
+}
+
+// CacheHit counts pages served from the cache(fresh or not modified).
+func (r *Recorder) CacheHit() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheHits++
+}
+
+// PagesReused counts pages taken from the previous run by the incremental crawl.
+func (r *Recorder) PagesReused(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reused += n
+}
+
+func (r *Recorder) PageFailed(page int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failedPages = append(r.failedPages, page)
+}
+
+// PageInterrupted counts pages canceled before they were fetched.
+func (r *Recorder) PageInterrupted() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interrupted++
+}
+
+func (r *Recorder) RowSeen() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rowsSeen++
+}
+
+func (r *Recorder) RowDropped(reason DropReason) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropped[reason]++
+}
+
+// Finish marks the end of the run, only the first call matters.
+func (r *Recorder) Finish() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.finishedAt.IsZero() {
+		r.finishedAt = time.Now()
+	}
+}
+
+// Report builds the summary, filteredBy is the rejections per filter name.
+func (r *Recorder) Report(filteredBy map[string]uint64) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	finishedAt := r.finishedAt
+	if finishedAt.IsZero() {
+		finishedAt = time.Now()
+	}
+	wall := finishedAt.Sub(r.startedAt)
+
+	dropped := make(map[DropReason]int, len(r.dropped))
+	droppedTotal := 0
+	for k, v := range r.dropped {
+		dropped[k] = v
+		droppedTotal += v
+	}
+
+	var rps float64
+	if wall > 0 {
+		rps = float64(r.requests) / wall.Seconds()
+	}
+
+	failedPages := slices.Clone(r.failedPages)
+	slices.Sort(failedPages)
+
+	return Report{
+		StartedAt:    r.startedAt.UTC(),
+		FinishedAt:   finishedAt.UTC(),
+		WallTimeMs:   wall.Milliseconds(),
+		EffectiveRPS: rps,
+		Pages: Pages{
+			Total:       r.totalPages,
+			Requests:    r.requests,
+			Fetched:     r.fetched,
+			Failed:      len(failedPages),
+			Interrupted: r.interrupted,
+			Retried:     r.retried,
+			CacheHits:   r.cacheHits,
+			Reused:      r.reused,
+			FailedPages: failedPages,
+		},
+		Rows: Rows{
+			Total:      r.totalRows,
+			Seen:       r.rowsSeen,
+			Accepted:   r.rowsSeen - droppedTotal,
+			Dropped:    dropped,
+			FilteredBy: filteredBy,
+		},
+		LatencyMs: latency(r.latencies),
+	}
+}
+
+// Write writes the report as JSON to the file, "-" or "stderr" means stderr.
+func (rep Report) Write(path string) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" || path == "stderr" {
+		_, err = os.Stderr.Write(data)
+		return err
+	}
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+func latency(samples []time.Duration) Latency {
+	if len(samples) == 0 {
+		return Latency{}
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	return Latency{
+		P50: ms(percentile(sorted, 50)),
+		P90: ms(percentile(sorted, 90)),
+		P99: ms(percentile(sorted, 99)),
+		Max: ms(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile by the nearest-rank method, samples must be sorted.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_Report(t *testing.T) {
+	r := NewRecorder()
+	r.SetTotals(3, 30)
+
+	for i := 1; i <= 4; i++ {
+		r.Request()
+	}
+	r.PageFetched(10 * time.Millisecond)
+	r.PageFetched(30 * time.Millisecond)
+	r.PageRetried()
+	r.PageFailed(3)
+
+	for i := 0; i < 5; i++ {
+		r.RowSeen()
+	}
+	r.RowDropped(DropNilTitle)
+	r.RowDropped(DropFiltered)
+	r.RowDropped(DropFiltered)
+	r.Finish()
+
+	rep := r.Report(map[string]uint64{"comments": 2})
+
+	assert.Equal(t, Pages{Total: 3, Requests: 4, Fetched: 2, Failed: 1, Retried: 1, FailedPages: []int{3}}, rep.Pages)
+	assert.Equal(t, Rows{
+		Total:      30,
+		Seen:       5,
+		Accepted:   2,
+		Dropped:    map[DropReason]int{DropNilTitle: 1, DropFiltered: 2},
+		FilteredBy: map[string]uint64{"comments": 2},
+	}, rep.Rows)
+	assert.Equal(t, Latency{P50: 10, P90: 30, P99: 30, Max: 30}, rep.LatencyMs)
+	assert.False(t, rep.FinishedAt.Before(rep.StartedAt))
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var r *Recorder
+
+	assert.NotPanics(t, func() {
+		r.SetTotals(1, 1)
+		r.Request()
+		r.PageFetched(time.Second)
+		r.PageRetried()
+		r.PageFailed(1)
+		r.RowSeen()
+		r.RowDropped(DropDeduped)
+		r.Finish()
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	samples := make([]time.Duration, 100)
+	for i := range samples {
+		samples[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := []struct {
+		p    int
+		want time.Duration
+	}{
+		{p: 0, want: time.Millisecond},
+		{p: 50, want: 50 * time.Millisecond},
+		{p: 90, want: 90 * time.Millisecond},
+		{p: 99, want: 99 * time.Millisecond},
+		{p: 100, want: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, percentile(samples, tt.p))
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	rep := NewRecorder().Report(nil)
+	require.NoError(t, rep.Write(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Contains(t, got, "pages")
+	assert.Contains(t, got, "rows")
+	assert.Contains(t, got, "page_latency_ms")
+}
@@ -0,0 +1,137 @@
+package runs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"articles-service/internal/output"
+	"articles-service/internal/report"
+)
+
+type (
+	// Run is the result of one crawl.
+	Run struct {
+		ID         string    `json:"id"`
+		StartedAt  time.Time `json:"started_at"`
+		FinishedAt time.Time `json:"finished_at"`
+		Error      string    `json:"error,omitempty"`
+		// set when the run was interrupted and the top is built from the pages done
+		Partial *Coverage       `json:"partial,omitempty"`
+		Top     []output.Record `json:"top"`
+		Authors []output.Record `json:"authors,omitempty"`
+		Domains []output.Record `json:"domains,omitempty"`
+		Windows []output.Record `json:"windows,omitempty"`
+		Stories []output.Record `json:"stories,omitempty"`
+		Report  report.Report   `json:"report"`
+	}
+	// Coverage of the partial run.
+	Coverage struct {
+		PagesDone  int `json:"pages_done"`
+		TotalPages int `json:"total_pages"`
+	}
+	// Summary is the short description of the run without results.
+	Summary struct {
+		ID         string    `json:"id"`
+		StartedAt  time.Time `json:"started_at"`
+		FinishedAt time.Time `json:"finished_at"`
+		Error      string    `json:"error,omitempty"`
+		Articles   int       `json:"articles"`
+	}
+	// History keeps the last runs in memory, safe for concurrent use.
+	History struct {
+		mu     sync.RWMutex
+		size   int
+		seq    uint64
+		runs   []*Run
+		latest *Run
+		failed uint64
+	}
+)
+
+// NewID returns sortable by time id of the run.
+func NewID(startedAt time.Time, seq uint64) string {
+	return fmt.Sprintf("%s-%d", startedAt.UTC().Format("20060102T150405Z"), seq)
+}
+
+func (r *Run) Summary() Summary {
+	return Summary{
+		ID:         r.ID,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		Error:      r.Error,
+		Articles:   len(r.Top),
+	}
+}
+
+func (r *Run) Failed() bool { return r.Error != "" }
+
+// NewHistory keeps up to size last runs.
+func NewHistory(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+	return &History{size: size, runs: make([]*Run, 0, size)}
+}
+
+// Add stores the run evicting the oldest one, the id is assigned if empty.
+// Failed runs are kept in history but never replace the latest successful result.
+func (h *History) Add(r *Run) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	if r.ID == "" {
+		r.ID = NewID(r.StartedAt, h.seq)
+	}
+
+	if len(h.runs) == h.size {
+		copy(h.runs, h.runs[1:])
+		h.runs = h.runs[:len(h.runs)-1]
+	}
+	h.runs = append(h.runs, r)
+
+	if r.Failed() {
+		h.failed++
+		return
+	}
+	h.latest = r
+}
+
+// Latest returns the last successful run, nil if there is none yet.
+func (h *History) Latest() *Run {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.latest
+}
+
+func (h *History) Get(id string) (*Run, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, r := range h.runs {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// List returns summaries of the kept runs, newest first.
+func (h *History) List() []Summary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	res := make([]Summary, 0, len(h.runs))
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		res = append(res, h.runs[i].Summary())
+	}
+	return res
+}
+
+// Stats returns the number of all added and failed runs.
+func (h *History) Stats() (total, failed uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.seq, h.failed
+}
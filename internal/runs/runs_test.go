@@ -0,0 +1,52 @@
+package runs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/output"
+)
+
+func TestHistory(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	h := NewHistory(2)
+	assert.Nil(t, h.Latest())
+
+	ok1 := &Run{StartedAt: at, Top: []output.Record{{Rank: 1, Title: "a"}}}
+	h.Add(ok1)
+	assert.Equal(t, "20240102T030405Z-1", ok1.ID)
+	assert.Same(t, ok1, h.Latest())
+
+	failed := &Run{StartedAt: at.Add(time.Minute), Error: "boom"}
+	h.Add(failed)
+	assert.Same(t, ok1, h.Latest(), "failed run doesn't replace the latest result")
+
+	ok2 := &Run{StartedAt: at.Add(2 * time.Minute)}
+	h.Add(ok2)
+	assert.Same(t, ok2, h.Latest())
+
+	_, found := h.Get(ok1.ID)
+	assert.False(t, found, "the oldest run is evicted")
+	got, found := h.Get(failed.ID)
+	require.True(t, found)
+	assert.Same(t, failed, got)
+
+	list := h.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, ok2.ID, list[0].ID, "newest first")
+	assert.Equal(t, "boom", list[1].Error)
+
+	total, failedTotal := h.Stats()
+	assert.Equal(t, uint64(3), total)
+	assert.Equal(t, uint64(1), failedTotal)
+}
+
+func TestRun_Summary(t *testing.T) {
+	r := &Run{ID: "id", Top: []output.Record{{Rank: 1}, {Rank: 2}}}
+	assert.Equal(t, Summary{ID: "id", Articles: 2}, r.Summary())
+	assert.False(t, r.Failed())
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Scheduler runs the job right away and then on every tick of the interval.
+// Runs never overlap: ticks passed while the job was running are late,
+// they are skipped and counted, the next run starts on the next tick.
+type Scheduler struct {
+	logger   *zap.Logger
+	interval time.Duration
+	clock    clock
+	skipped  atomic.Uint64
+	runs     atomic.Uint64
+}
+
+// clock is replaced in tests.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func New(logger *zap.Logger, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		logger:   logger,
+		interval: interval,
+		clock:    realClock{},
+	}
+}
+
+// Run blocks until the context is canceled.
+func (s *Scheduler) Run(ctx context.Context, job func(ctx context.Context)) {
+	next := s.clock.Now()
+	for {
+		s.runs.Add(1)
+		job(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		next = next.Add(s.interval)
+		if late := s.clock.Now().Sub(next); late >= 0 {
+			missed := uint64(late/s.interval) + 1
+			s.skipped.Add(missed)
+			next = next.Add(time.Duration(missed) * s.interval)
+			s.logger.Warn("run took longer than the interval, skipping late ticks",
+				zap.Uint64("skipped", missed), zap.Duration("interval", s.interval))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(next.Sub(s.clock.Now())):
+		}
+	}
+}
+
+// Skipped returns the number of skipped late ticks.
+func (s *Scheduler) Skipped() uint64 { return s.skipped.Load() }
+
+// Runs returns the number of started runs.
+func (s *Scheduler) Runs() uint64 { return s.runs.Load() }
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeClock moves only when the job works or the scheduler waits, so runs are deterministic.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestScheduler_Run(t *testing.T) {
+	const interval = 20 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		jobDuration time.Duration
+		runs        int
+		wantSkipped uint64
+		// between starts of the first and the second run
+		wantGap time.Duration
+	}{
+		{name: "fast job runs on every tick", jobDuration: 0, runs: 3, wantGap: interval},
+		{name: "job on the tick skips it", jobDuration: interval, runs: 2, wantSkipped: 1, wantGap: 2 * interval},
+		{
+			name:        "slow job skips late ticks",
+			jobDuration: 2*interval + interval/2,
+			runs:        2,
+			wantSkipped: 2,
+			// the next run starts on the tick, not right after the late job
+			wantGap: 3 * interval,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+			s := New(zap.NewNop(), interval)
+			s.clock = clock
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var running, overlapped atomic.Int32
+			var starts []time.Time
+			s.Run(ctx, func(ctx context.Context) {
+				if running.Add(1) > 1 {
+					overlapped.Add(1)
+				}
+				defer running.Add(-1)
+
+				starts = append(starts, clock.Now())
+				clock.now = clock.now.Add(tt.jobDuration)
+				if len(starts) == tt.runs {
+					cancel()
+				}
+			})
+
+			assert.Len(t, starts, tt.runs)
+			assert.Equal(t, uint64(tt.runs), s.Runs())
+			assert.Zero(t, overlapped.Load())
+			assert.Equal(t, tt.wantSkipped, s.Skipped())
+			assert.Equal(t, tt.wantGap, starts[1].Sub(starts[0]))
+		})
+	}
+}
+
+func TestScheduler_Run_StopsOnCancel(t *testing.T) {
+	s := New(zap.NewNop(), time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, func(context.Context) {})
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler didn't stop")
+	}
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal/feed"
+	"articles-service/internal/output"
+	"articles-service/internal/report"
+	"articles-service/internal/runs"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+type (
+	// Server exposes results of the scheduled crawls over HTTP.
+	Server struct {
+		logger    *zap.Logger
+		addr      string
+		history   *runs.History
+		feed      feed.Meta
+		startedAt time.Time
+		hub       *hub
+		heartbeat time.Duration
+		skipped   func() uint64
+		metrics   http.Handler
+	}
+	errorResponse struct {
+		Error string `json:"error"`
+	}
+	statsResponse struct {
+		StartedAt  time.Time `json:"started_at"`
+		UptimeSec  int64     `json:"uptime_sec"`
+		RunsTotal  uint64    `json:"runs_total"`
+		RunsFailed uint64    `json:"runs_failed"`
+		// late ticks skipped by the scheduler
+		SkippedTicks uint64         `json:"skipped_ticks"`
+		LastRun      *runs.Summary  `json:"last_run,omitempty"`
+		LatestRun    *runs.Summary  `json:"latest_successful_run,omitempty"`
+		Report       *report.Report `json:"report,omitempty"`
+	}
+)
+
+func New(logger *zap.Logger, addr string, history *runs.History, feedMeta feed.Meta) *Server {
+	return &Server{
+		logger:    logger,
+		addr:      addr,
+		history:   history,
+		feed:      feedMeta,
+		startedAt: time.Now(),
+		hub:       newHub(logger),
+		heartbeat: heartbeatInterval,
+	}
+}
+
+// SetMetrics serves the metrics handler at /metrics, must be called before Run.
+func (s *Server) SetMetrics(h http.Handler) {
+	s.metrics = h
+}
+
+// SetSkippedTicks sets the source of the skipped scheduler ticks for stats.
+func (s *Server) SetSkippedTicks(fn func() uint64) {
+	s.skipped = fn
+}
+
+// Publish notifies stream clients about the new successful run.
+func (s *Server) Publish(run *runs.Run) {
+	s.hub.publish(run)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/top", s.top)
+	mux.HandleFunc("GET /v1/top/stream", s.stream)
+	mux.HandleFunc("GET /v1/authors", s.authors)
+	mux.HandleFunc("GET /v1/stats", s.stats)
+	mux.HandleFunc("GET /v1/runs", s.listRuns)
+	mux.HandleFunc("GET /v1/runs/{id}", s.getRun)
+	if s.metrics != nil {
+		mux.Handle("GET /metrics", s.metrics)
+	}
+
+	return mux
+}
+
+// Run serves until the context is canceled and then shuts down gracefully
+// letting in-flight requests finish.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("http server listening", zap.String("addr", s.addr))
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("http server: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.hub.close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("http server shutdown: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	s.logger.Info("http server gracefully stopped")
+
+	return nil
+}
+
+// top: GET /v1/top?limit=&format=
+func (s *Server) top(w http.ResponseWriter, r *http.Request) {
+	s.records(w, r, func(run *runs.Run) []output.Record { return run.Top })
+}
+
+// authors: GET /v1/authors?limit=&format=
+func (s *Server) authors(w http.ResponseWriter, r *http.Request) {
+	s.records(w, r, func(run *runs.Run) []output.Record { return run.Authors })
+}
+
+func (s *Server) records(w http.ResponseWriter, r *http.Request, get func(run *runs.Run) []output.Record) {
+	run := s.history.Latest()
+	if run == nil {
+		writeError(w, http.StatusServiceUnavailable, "no results yet, the first crawl is in progress")
+		return
+	}
+
+	records := get(run)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if limit < len(records) {
+			records = records[:limit]
+		}
+	}
+
+	format := output.JSON
+	if v := r.URL.Query().Get("format"); v != "" {
+		f, err := output.ParseFormat(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		format = f
+	}
+
+	var writer output.Writer
+	if format.IsFeed() {
+		meta := s.feed
+		meta.SelfLink = selfLink(r)
+		meta.Updated = run.FinishedAt
+		writer = output.NewFeed(format, meta)
+	} else {
+		var err error
+		if writer, err = output.NewWriter(format); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", output.ContentType(format))
+	w.Header().Set("X-Run-Id", run.ID)
+	if err := writer.Write(w, records); err != nil {
+		s.logger.Error("cannot write response", zap.Error(err))
+	}
+}
+
+// stats: GET /v1/stats
+func (s *Server) stats(w http.ResponseWriter, _ *http.Request) {
+	total, failed := s.history.Stats()
+	resp := statsResponse{
+		StartedAt:  s.startedAt.UTC(),
+		UptimeSec:  int64(time.Since(s.startedAt).Seconds()),
+		RunsTotal:  total,
+		RunsFailed: failed,
+	}
+	if s.skipped != nil {
+		resp.SkippedTicks = s.skipped()
+	}
+	if list := s.history.List(); len(list) != 0 {
+		resp.LastRun = &list[0]
+	}
+	if run := s.history.Latest(); run != nil {
+		summary := run.Summary()
+		resp.LatestRun = &summary
+		resp.Report = &run.Report
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// listRuns: GET /v1/runs
+func (s *Server) listRuns(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.history.List())
+}
+
+// getRun: GET /v1/runs/{id}
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.history.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func selfLink(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"articles-service/internal/feed"
+	"articles-service/internal/output"
+	"articles-service/internal/runs"
+)
+
+func newTestServer(t *testing.T, history *runs.History) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(New(zap.NewNop(), "", history, feed.Meta{Title: "Top"}).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestServer(t *testing.T) {
+	history := runs.NewHistory(10)
+	run := &runs.Run{
+		StartedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		FinishedAt: time.Date(2024, 1, 2, 3, 5, 0, 0, time.UTC),
+		Top: []output.Record{
+			{Rank: 1, Title: "a", Comments: 3, URL: "https://a.com"},
+			{Rank: 2, Title: "b", Comments: 2},
+			{Rank: 3, Title: "c", Comments: 1},
+		},
+		Authors: []output.Record{{Rank: 1, Author: "x", Comments: 6, Articles: 3}},
+	}
+	history.Add(run)
+	history.Add(&runs.Run{StartedAt: run.StartedAt.Add(time.Minute), Error: "upstream is down"})
+
+	srv := newTestServer(t, history)
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantType    string
+		wantContain []string
+	}{
+		{
+			name:        "top json by default with limit",
+			path:        "/v1/top?limit=2",
+			wantStatus:  http.StatusOK,
+			wantType:    "application/json",
+			wantContain: []string{`"title": "a"`, `"title": "b"`},
+		},
+		{
+			name:        "top csv",
+			path:        "/v1/top?format=csv",
+			wantStatus:  http.StatusOK,
+			wantType:    "text/csv; charset=utf-8",
+			wantContain: []string{"rank,title,comments,url\n1,a,3,https://a.com\n"},
+		},
+		{
+			name:        "top rss with self link",
+			path:        "/v1/top?format=rss",
+			wantStatus:  http.StatusOK,
+			wantType:    "application/rss+xml; charset=utf-8",
+			wantContain: []string{"<title>Top</title>", `rel="self"`, "/v1/top?format=rss"},
+		},
+		{
+			name:        "authors",
+			path:        "/v1/authors?format=text",
+			wantStatus:  http.StatusOK,
+			wantType:    "text/plain; charset=utf-8",
+			wantContain: []string{"x\t6\t3\n"},
+		},
+		{
+			name:        "invalid limit",
+			path:        "/v1/top?limit=0",
+			wantStatus:  http.StatusBadRequest,
+			wantContain: []string{"limit must be a positive integer"},
+		},
+		{
+			name:        "invalid format",
+			path:        "/v1/top?format=xml",
+			wantStatus:  http.StatusBadRequest,
+			wantContain: []string{"unknown format"},
+		},
+		{
+			name:        "stats",
+			path:        "/v1/stats",
+			wantStatus:  http.StatusOK,
+			wantType:    "application/json",
+			wantContain: []string{`"runs_total":2`, `"runs_failed":1`, `"error":"upstream is down"`, `"latest_successful_run":{"id":"` + run.ID},
+		},
+		{
+			name:        "run by id",
+			path:        "/v1/runs/" + run.ID,
+			wantStatus:  http.StatusOK,
+			wantContain: []string{`"id":"` + run.ID + `"`, `"top":[`},
+		},
+		{
+			name:        "unknown run",
+			path:        "/v1/runs/unknown",
+			wantStatus:  http.StatusNotFound,
+			wantContain: []string{"run not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := get(t, srv.URL+tt.path)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode, body)
+			if tt.wantType != "" {
+				assert.Equal(t, tt.wantType, resp.Header.Get("Content-Type"))
+			}
+			for _, s := range tt.wantContain {
+				assert.Contains(t, body, s)
+			}
+		})
+	}
+
+	t.Run("top limit", func(t *testing.T) {
+		_, body := get(t, srv.URL+"/v1/top?limit=2")
+		var records []output.Record
+		require.NoError(t, json.Unmarshal([]byte(body), &records))
+		assert.Len(t, records, 2)
+	})
+
+	t.Run("runs list", func(t *testing.T) {
+		_, body := get(t, srv.URL+"/v1/runs")
+		var list []runs.Summary
+		require.NoError(t, json.Unmarshal([]byte(body), &list))
+		require.Len(t, list, 2)
+		assert.True(t, strings.HasSuffix(list[1].ID, "-1"))
+	})
+}
+
+func TestServer_NoResultsYet(t *testing.T) {
+	srv := newTestServer(t, runs.NewHistory(1))
+
+	resp, body := get(t, srv.URL+"/v1/top")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, body, "no results yet")
+
+	resp, _ = get(t, srv.URL+"/v1/stats")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_Run_GracefulShutdown(t *testing.T) {
+	s := New(zap.NewNop(), "127.0.0.1:0", runs.NewHistory(1), feed.Meta{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal/diff"
+	"articles-service/internal/output"
+	"articles-service/internal/runs"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	// slow consumer which didn't read that many events is dropped
+	clientBuffer = 16
+)
+
+type (
+	// hub broadcasts top list changes to SSE clients.
+	hub struct {
+		logger  *zap.Logger
+		mu      sync.Mutex
+		clients map[*client]struct{}
+		last    *runs.Run
+		closed  bool
+	}
+	client struct {
+		events chan event
+	}
+	event struct {
+		id   string
+		name string
+		data []byte
+	}
+	snapshotEvent struct {
+		RunID string          `json:"run_id"`
+		Top   []output.Record `json:"top"`
+	}
+	diffEvent struct {
+		RunID     string        `json:"run_id"`
+		PrevRunID string        `json:"prev_run_id"`
+		Changes   []diff.Change `json:"changes"`
+	}
+)
+
+func newHub(logger *zap.Logger) *hub {
+	return &hub{
+		logger:  logger,
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// publish sends the diff against the previously published run to all clients,
+// nothing is sent if the ranking didn't change. The first published run is sent
+// as the snapshot, clients connected before it got the empty one.
+func (h *hub) publish(run *runs.Run) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	prev := h.last
+	h.last = run
+	if h.closed {
+		return
+	}
+
+	if prev == nil {
+		data, err := json.Marshal(newSnapshotEvent(run))
+		if err != nil {
+			h.logger.Error("cannot marshal snapshot event", zap.Error(err))
+			return
+		}
+		h.broadcast(event{id: run.ID, name: "snapshot", data: data})
+		return
+	}
+
+	d := diff.Compare(prev.Top, run.Top)
+	if d.Empty() {
+		return
+	}
+	data, err := json.Marshal(diffEvent{RunID: run.ID, PrevRunID: prev.ID, Changes: d.Changes})
+	if err != nil {
+		h.logger.Error("cannot marshal diff event", zap.Error(err))
+		return
+	}
+	h.broadcast(event{id: run.ID, name: "diff", data: data})
+}
+
+// broadcast must be called under the lock.
+func (h *hub) broadcast(ev event) {
+	for c := range h.clients {
+		select {
+		case c.events <- ev:
+		default:
+			h.logger.Warn("dropping slow SSE client")
+			h.remove(c)
+		}
+	}
+}
+
+// subscribe registers the client and returns the snapshot of the last published run
+// atomically so the client can't miss a diff between the snapshot and the subscription.
+func (h *hub) subscribe() (*client, *runs.Run, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, false
+	}
+	c := &client{events: make(chan event, clientBuffer)}
+	h.clients[c] = struct{}{}
+
+	return c, h.last, true
+}
+
+func (h *hub) unsubscribe(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(c)
+}
+
+// close disconnects all clients, streams would block the graceful shutdown otherwise.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		h.remove(c)
+	}
+}
+
+// remove must be called under the lock.
+func (h *hub) remove(c *client) {
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	close(c.events)
+}
+
+// stream: GET /v1/top/stream
+// pushes the full snapshot on connect and then diff events on ranking changes.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	c, last, ok := s.hub.subscribe()
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "server is shutting down")
+		return
+	}
+	defer s.hub.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	snapshot := newSnapshotEvent(last)
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		s.logger.Error("cannot marshal snapshot event", zap.Error(err))
+		return
+	}
+	if err = writeEvent(w, event{id: snapshot.RunID, name: "snapshot", data: data}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-c.events:
+			// dropped as slow consumer or the server is shutting down
+			if !ok {
+				return
+			}
+			if err = writeEvent(w, ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// newSnapshotEvent returns the empty snapshot when nothing was published yet.
+func newSnapshotEvent(run *runs.Run) snapshotEvent {
+	if run == nil {
+		return snapshotEvent{Top: []output.Record{}}
+	}
+	ev := snapshotEvent{RunID: run.ID, Top: run.Top}
+	if ev.Top == nil {
+		ev.Top = []output.Record{}
+	}
+	return ev
+}
+
+func writeEvent(w http.ResponseWriter, ev event) error {
+	if ev.id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", ev.id); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+	return err
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"articles-service/internal/diff"
+	"articles-service/internal/feed"
+	"articles-service/internal/output"
+	"articles-service/internal/runs"
+)
+
+type sseEvent struct {
+	id, name, data string
+}
+
+// readEvent reads the next event skipping comments(heartbeats) unless asked.
+func readEvent(t *testing.T, r *bufio.Reader, withComments bool) sseEvent {
+	t.Helper()
+
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			if ev != (sseEvent{}) {
+				return ev
+			}
+		case strings.HasPrefix(line, ":"):
+			if withComments {
+				return sseEvent{name: strings.TrimSpace(strings.TrimPrefix(line, ":"))}
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestServer_Stream(t *testing.T) {
+	s := New(zap.NewNop(), "", runs.NewHistory(1), feed.Meta{})
+	s.heartbeat = 50 * time.Millisecond
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	s.Publish(&runs.Run{ID: "run-1", Top: []output.Record{
+		{Rank: 1, Title: "a", Comments: 10},
+		{Rank: 2, Title: "b", Comments: 5},
+	}})
+
+	resp, err := http.Get(srv.URL + "/v1/top/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+
+	ev := readEvent(t, r, false)
+	assert.Equal(t, "snapshot", ev.name)
+	assert.Equal(t, "run-1", ev.id)
+	var snapshot snapshotEvent
+	require.NoError(t, json.Unmarshal([]byte(ev.data), &snapshot))
+	assert.Len(t, snapshot.Top, 2)
+
+	// unchanged ranking sends nothing, then the changed one sends the diff
+	s.Publish(&runs.Run{ID: "run-2", Top: snapshot.Top})
+	s.Publish(&runs.Run{ID: "run-3", Top: []output.Record{
+		{Rank: 1, Title: "b", Comments: 12},
+		{Rank: 2, Title: "c", Comments: 7},
+	}})
+
+	ev = readEvent(t, r, false)
+	assert.Equal(t, "diff", ev.name)
+	assert.Equal(t, "run-3", ev.id)
+	var d diffEvent
+	require.NoError(t, json.Unmarshal([]byte(ev.data), &d))
+	assert.Equal(t, "run-2", d.PrevRunID)
+	assert.Equal(t, []diff.ChangeType{diff.Entered, diff.Left, diff.Moved}, changeTypes(d.Changes))
+
+	ev = readEvent(t, r, true)
+	assert.Equal(t, "heartbeat", ev.name)
+}
+
+func TestServer_Stream_BeforeFirstRun(t *testing.T) {
+	s := New(zap.NewNop(), "", runs.NewHistory(1), feed.Meta{})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/top/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+
+	ev := readEvent(t, r, false)
+	assert.Equal(t, "snapshot", ev.name)
+	assert.Empty(t, ev.id)
+	assert.JSONEq(t, `{"run_id":"","top":[]}`, ev.data)
+
+	// the first run has nothing to diff against, the client gets it as the snapshot
+	s.Publish(&runs.Run{ID: "run-1", Top: []output.Record{{Rank: 1, Title: "a", Comments: 10}}})
+
+	ev = readEvent(t, r, false)
+	assert.Equal(t, "snapshot", ev.name)
+	assert.Equal(t, "run-1", ev.id)
+	var snapshot snapshotEvent
+	require.NoError(t, json.Unmarshal([]byte(ev.data), &snapshot))
+	assert.Equal(t, "run-1", snapshot.RunID)
+	assert.Len(t, snapshot.Top, 1)
+}
+
+func TestHub_DropsSlowConsumer(t *testing.T) {
+	h := newHub(zap.NewNop())
+	c, last, ok := h.subscribe()
+	require.True(t, ok)
+	assert.Nil(t, last)
+
+	h.publish(&runs.Run{ID: "0"})
+	for i := 0; i <= clientBuffer; i++ {
+		h.publish(&runs.Run{ID: "run", Top: []output.Record{{Rank: 1, Title: strings.Repeat("a", i+1)}}})
+	}
+
+	received := 0
+	for range c.events {
+		received++
+	}
+	assert.Equal(t, clientBuffer, received, "the client is dropped when the buffer is full")
+	assert.Empty(t, h.clients)
+}
+
+func TestHub_Close(t *testing.T) {
+	h := newHub(zap.NewNop())
+	c, _, ok := h.subscribe()
+	require.True(t, ok)
+
+	h.close()
+
+	_, open := <-c.events
+	assert.False(t, open)
+
+	_, _, ok = h.subscribe()
+	assert.False(t, ok, "no new clients after close")
+}
+
+func changeTypes(changes []diff.Change) []diff.ChangeType {
+	res := make([]diff.ChangeType, len(changes))
+	for i, c := range changes {
+		res[i] = c.Type
+	}
+	return res
+}
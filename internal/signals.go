@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// signals by name for -stop-signals
+var signalNames = map[string]os.Signal{
+	"INT":  syscall.SIGINT,
+	"TERM": syscall.SIGTERM,
+	"QUIT": syscall.SIGQUIT,
+	"HUP":  syscall.SIGHUP,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+var defaultStopSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// parseSignals parses comma separated names: TERM or SIGTERM, case-insensitive.
+func parseSignals(s string) ([]os.Signal, error) {
+	var sigs []os.Signal
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+		if name == "" {
+			continue
+		}
+		sig, ok := signalNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown signal %q, expected INT, TERM, QUIT, HUP, USR1 or USR2", name)
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
+
+// signalDispatcher cancels the context on termination signals
+// and runs handlers of other signals one at a time.
+type signalDispatcher struct {
+	terminate []os.Signal
+	handlers  map[os.Signal]func()
+}
+
+func newSignalDispatcher(terminate ...os.Signal) *signalDispatcher {
+	return &signalDispatcher{
+		terminate: terminate,
+		handlers:  make(map[os.Signal]func()),
+	}
+}
+
+// Handle runs fn on every sig instead of the default action, must be called before Start.
+func (d *signalDispatcher) Handle(sig os.Signal, fn func()) {
+	d.handlers[sig] = fn
+}
+
+// Start returns the context canceled with the parent or by the first termination signal,
+// the signal is the cause of the cancellation. Stop restores the default signal actions.
+func (d *signalDispatcher) Start(parent context.Context) (ctx context.Context, stop func()) {
+	sigs := append([]os.Signal(nil), d.terminate...)
+	for sig := range d.handlers {
+		sigs = append(sigs, sig)
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	ctx, cancel := context.WithCancelCause(parent)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				if fn, ok := d.handlers[sig]; ok {
+					fn()
+					continue
+				}
+				cancel(fmt.Errorf("received %v", sig))
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(ch)
+		cancel(nil)
+		<-done
+	}
+}
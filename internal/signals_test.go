@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignalDispatcher(t *testing.T) {
+	handled := make(chan struct{}, 1)
+	d := newSignalDispatcher(syscall.SIGUSR2)
+	d.Handle(syscall.SIGHUP, func() { handled <- struct{}{} })
+	ctx, stop := d.Start(context.Background())
+	defer stop()
+
+	// handled signals don't cancel
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("SIGHUP is not handled")
+	}
+	assert.NoError(t, ctx.Err())
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("SIGUSR2 doesn't cancel")
+	}
+	assert.EqualError(t, context.Cause(ctx), "received user defined signal 2")
+}
+
+func TestSignalDispatcher_Parent(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, stop := newSignalDispatcher(syscall.SIGUSR2).Start(parent)
+	defer stop()
+
+	cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("parent cancellation is ignored")
+	}
+}
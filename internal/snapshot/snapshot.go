@@ -0,0 +1,261 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"articles-service/internal/output"
+	"articles-service/internal/runs"
+)
+
+const (
+	// Latest refers to the newest stored run.
+	Latest = "latest"
+
+	indexFile = "index.json"
+	runsDir   = "runs"
+)
+
+var ErrNotFound = errors.New("snapshot not found")
+
+type (
+	// Snapshot is the full result of one run and the parameters it was started with,
+	// run fields are inlined so the file is readable as a run(e.g. by the diff command).
+	Snapshot struct {
+		runs.Run
+		Params map[string]string `json:"params,omitempty"`
+	}
+	// Entry is the index record of the stored snapshot.
+	Entry struct {
+		runs.Summary
+		File string `json:"file"`
+	}
+	// Store keeps a file per run and the index of them in the directory:
+	//
+	//	dir/index.json
+	//	dir/runs/<id>.json
+	//
+	// The index is rebuilt from run files when it is missing or broken.
+	// Safe for concurrent use within one process, one writer per directory is expected.
+	Store struct {
+		mu      sync.Mutex
+		dir     string
+		entries []Entry // oldest first
+	}
+	// PruneOptions keep runs matching all of the set conditions.
+	PruneOptions struct {
+		// Keep is the number of newest runs to keep, 0 - unlimited
+		Keep int
+		// MaxAge removes runs started before Now-MaxAge, 0 - unlimited
+		MaxAge time.Duration
+		Now    time.Time
+	}
+)
+
+// Open opens the store creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, runsDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &Store{dir: dir}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) Dir() string { return s.dir }
+
+// Put stores the snapshot, the id is assigned if empty and made unique if already stored.
+func (s *Store) Put(snap *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if snap.ID == "" {
+		snap.ID = runs.NewID(snap.StartedAt, 1)
+	}
+	id := snap.ID
+	for i := 2; s.index(snap.ID) != -1; i++ {
+		snap.ID = id + "." + strconv.Itoa(i)
+	}
+
+	file := filepath.Join(runsDir, snap.ID+".json")
+	if err := writeJSON(filepath.Join(s.dir, file), snap); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	s.entries = append(s.entries, Entry{Summary: snap.Summary(), File: file})
+	// runs may finish out of order
+	slices.SortStableFunc(s.entries, func(a, b Entry) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	return s.writeIndex()
+}
+
+// List returns the stored runs, newest first.
+func (s *Store) List() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := slices.Clone(s.entries)
+	slices.Reverse(res)
+	return res
+}
+
+// Get returns the snapshot by id, Latest or Latest~N(N runs before the latest).
+func (s *Store) Get(id string) (*Snapshot, error) {
+	s.mu.Lock()
+	i, err := s.resolve(id)
+	var file string
+	if err == nil {
+		file = s.entries[i].File
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, file))
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return &snap, nil
+}
+
+// Prune removes runs not matching the options and returns their ids.
+func (s *Store) Prune(opts PruneOptions) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	var (
+		kept    = make([]Entry, 0, len(s.entries))
+		removed []string
+	)
+	for i, e := range s.entries {
+		tooOld := opts.MaxAge > 0 && e.StartedAt.Before(opts.Now.Add(-opts.MaxAge))
+		tooMany := opts.Keep > 0 && len(s.entries)-i > opts.Keep
+		if !tooOld && !tooMany {
+			kept = append(kept, e)
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(s.dir, e.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, e.ID)
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	s.entries = kept
+
+	return removed, s.writeIndex()
+}
+
+func (s *Store) resolve(id string) (int, error) {
+	if rest, ok := strings.CutPrefix(id, Latest); ok {
+		back := 0
+		if rest != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(rest, "~"))
+			if err != nil || !strings.HasPrefix(rest, "~") || n < 0 {
+				return -1, fmt.Errorf("invalid snapshot reference %q, expected %s or %s~N", id, Latest, Latest)
+			}
+			back = n
+		}
+		i := len(s.entries) - 1 - back
+		if i < 0 {
+			return -1, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return i, nil
+	}
+
+	i := s.index(id)
+	if i == -1 {
+		return -1, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return i, nil
+}
+
+func (s *Store) index(id string) int {
+	return slices.IndexFunc(s.entries, func(e Entry) bool { return e.ID == id })
+}
+
+// load reads the index or rebuilds it from run files.
+func (s *Store) load() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if err == nil && json.Unmarshal(data, &s.entries) == nil {
+		return nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return s.rebuild()
+}
+
+func (s *Store) rebuild() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, runsDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	s.entries = s.entries[:0]
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var snap Snapshot
+		// skip broken files, e.g. written by hand
+		if json.Unmarshal(data, &snap) != nil || snap.ID == "" {
+			continue
+		}
+		s.entries = append(s.entries, Entry{Summary: snap.Summary(), File: filepath.Join(runsDir, filepath.Base(path))})
+	}
+	slices.SortStableFunc(s.entries, func(a, b Entry) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	if len(s.entries) == 0 {
+		return nil
+	}
+	return s.writeIndex()
+}
+
+func (s *Store) writeIndex() error {
+	if err := writeJSON(filepath.Join(s.dir, indexFile), s.entries); err != nil {
+		return fmt.Errorf("write snapshot index: %w", err)
+	}
+	return nil
+}
+
+// writeJSON replaces the file atomically, so readers never see a partial file.
+func writeJSON(path string, v any) error {
+	return output.WriteFile(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
@@ -0,0 +1,123 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/output"
+	"articles-service/internal/runs"
+)
+
+var at = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+func newSnapshot(id string, startedAt time.Time, titles ...string) *Snapshot {
+	snap := &Snapshot{
+		Run:    runs.Run{ID: id, StartedAt: startedAt, FinishedAt: startedAt.Add(time.Second)},
+		Params: map[string]string{"l": "10"},
+	}
+	for i, title := range titles {
+		snap.Top = append(snap.Top, output.Record{Rank: i + 1, Title: title, Comments: uint64(10 - i)})
+	}
+	return snap
+}
+
+func TestStore_PutGetList(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put(newSnapshot("b", at.Add(time.Hour), "x", "y")))
+	require.NoError(t, s.Put(newSnapshot("a", at, "x")))
+	dup := newSnapshot("b", at.Add(2*time.Hour), "z")
+	require.NoError(t, s.Put(dup))
+	assert.Equal(t, "b.2", dup.ID)
+
+	ids := func(entries []Entry) []string {
+		res := make([]string, len(entries))
+		for i, e := range entries {
+			res[i] = e.ID
+		}
+		return res
+	}
+	assert.Equal(t, []string{"b.2", "b", "a"}, ids(s.List()))
+
+	got, err := s.Get("b")
+	require.NoError(t, err)
+	assert.Equal(t, newSnapshot("b", at.Add(time.Hour), "x", "y"), got)
+
+	tests := []struct {
+		ref     string
+		wantID  string
+		wantErr bool
+	}{
+		{ref: Latest, wantID: "b.2"},
+		{ref: Latest + "~2", wantID: "a"},
+		{ref: Latest + "~3", wantErr: true},
+		{ref: Latest + "2", wantErr: true},
+		{ref: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := s.Get(tt.ref)
+		if tt.wantErr {
+			assert.Error(t, err, tt.ref)
+			continue
+		}
+		require.NoError(t, err, tt.ref)
+		assert.Equal(t, tt.wantID, got.ID)
+	}
+
+	// reopened store reads the index
+	s, err = Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.2", "b", "a"}, ids(s.List()))
+
+	// and rebuilds it when missing
+	require.NoError(t, os.Remove(filepath.Join(dir, indexFile)))
+	s, err = Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.2", "b", "a"}, ids(s.List()))
+}
+
+func TestStore_Prune(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        PruneOptions
+		wantRemoved []string
+		wantLeft    int
+	}{
+		{name: "keep", opts: PruneOptions{Keep: 2}, wantRemoved: []string{"1", "2"}, wantLeft: 2},
+		{name: "max age", opts: PruneOptions{MaxAge: 90 * time.Minute, Now: at.Add(3 * time.Hour)}, wantRemoved: []string{"1", "2"}, wantLeft: 2},
+		{name: "both", opts: PruneOptions{Keep: 3, MaxAge: 150 * time.Minute, Now: at.Add(3 * time.Hour)}, wantRemoved: []string{"1"}, wantLeft: 3},
+		{name: "nothing", opts: PruneOptions{Keep: 10}, wantLeft: 4},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := Open(dir)
+			require.NoError(t, err)
+			for i, id := range []string{"1", "2", "3", "4"} {
+				require.NoError(t, s.Put(newSnapshot(id, at.Add(time.Duration(i)*time.Hour), "x")))
+			}
+
+			removed, err := s.Prune(tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRemoved, removed)
+			assert.Len(t, s.List(), tt.wantLeft)
+
+			files, err := filepath.Glob(filepath.Join(dir, runsDir, "*.json"))
+			require.NoError(t, err)
+			assert.Len(t, files, tt.wantLeft)
+			for _, id := range tt.wantRemoved {
+				_, err = s.Get(id)
+				assert.ErrorIs(t, err, ErrNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"articles-service/internal/output"
+	"articles-service/internal/snapshot"
+	"articles-service/internal/window"
+)
+
+const defaultSnapshotDir = "snapshots"
+
+// snapshotsFlags are the flags of the snapshots commands.
+type snapshotsFlags struct {
+	dir       string
+	format    string
+	n         int
+	keep      int
+	olderThan string
+}
+
+// newSnapshotsFlagSet defines the flags of the snapshots command, the completion walks them too.
+func newSnapshotsFlagSet(cmd string, f *snapshotsFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("snapshots "+cmd, flag.ContinueOnError)
+	fs.StringVar(&f.dir, "snapshot-dir", defaultSnapshotDir, "snapshot store directory")
+
+	switch cmd {
+	case "list":
+		fs.StringVar(&f.format, "format", "table", "output format: table|json")
+		fs.IntVar(&f.n, "n", 0, "list only the newest N runs(0 - all)")
+	case "show":
+		fs.StringVar(&f.format, "format", "snapshot", "snapshot(full JSON) or the output format of the top: "+strings.Join(output.Formats(), "|"))
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "usage: top-articles snapshots show [flags] ID|%s|%s~N\n", snapshot.Latest, snapshot.Latest)
+			fs.PrintDefaults()
+		}
+	case "prune":
+		fs.IntVar(&f.keep, "keep", 0, "keep only the newest N runs(0 - unlimited)")
+		fs.StringVar(&f.olderThan, "older-than", "", "remove runs started earlier than the duration ago(36h, 30d, 4w)")
+	}
+
+	return fs
+}
+
+// RunSnapshots runs the snapshots command: list, show and prune stored runs.
+//
+//	snapshots list|show|prune [flags]
+func RunSnapshots(_ context.Context, argv []string) error {
+	usage := func(w io.Writer) {
+		fmt.Fprintln(w, "usage: top-articles snapshots list|show|prune [flags]")
+	}
+	if len(argv) == 0 {
+		usage(os.Stderr)
+		return errors.New("snapshots expects a command")
+	}
+
+	cmd, argv := argv[0], argv[1:]
+	switch cmd {
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+		return nil
+	}
+	var f snapshotsFlags
+	fs := newSnapshotsFlagSet(cmd, &f)
+
+	switch cmd {
+	case "list":
+		if err := fs.Parse(argv); err != nil {
+			return err
+		}
+		store, err := snapshot.Open(f.dir)
+		if err != nil {
+			return err
+		}
+		entries := store.List()
+		if f.n > 0 && len(entries) > f.n {
+			entries = entries[:f.n]
+		}
+		return listSnapshots(os.Stdout, entries, f.format)
+
+	case "show":
+		if err := fs.Parse(argv); err != nil {
+			return err
+		}
+		id := snapshot.Latest
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return errors.New("show expects one snapshot id")
+		}
+		if fs.NArg() == 1 {
+			id = fs.Arg(0)
+		}
+		store, err := snapshot.Open(f.dir)
+		if err != nil {
+			return err
+		}
+		snap, err := store.Get(id)
+		if err != nil {
+			return err
+		}
+		return showSnapshot(os.Stdout, snap, f.format)
+
+	case "prune":
+		if err := fs.Parse(argv); err != nil {
+			return err
+		}
+		opts := snapshot.PruneOptions{Keep: f.keep}
+		if f.olderThan != "" {
+			d, err := window.ParseDuration(f.olderThan)
+			if err != nil {
+				return fmt.Errorf("older-than: %w", err)
+			}
+			opts.MaxAge = d
+		}
+		if opts.Keep <= 0 && opts.MaxAge <= 0 {
+			return errors.New("prune expects -keep and/or -older-than")
+		}
+		store, err := snapshot.Open(f.dir)
+		if err != nil {
+			return err
+		}
+		removed, err := store.Prune(opts)
+		fmt.Fprintf(os.Stdout, "removed %d runs\n", len(removed))
+		return err
+
+	default:
+		usage(os.Stderr)
+		return fmt.Errorf("unknown snapshots command %q", cmd)
+	}
+}
+
+func listSnapshots(w io.Writer, entries []snapshot.Entry, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTARTED\tDURATION\tARTICLES\tERROR")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+				e.ID,
+				e.StartedAt.Format(time.DateTime),
+				e.FinishedAt.Sub(e.StartedAt).Round(time.Millisecond),
+				e.Articles,
+				e.Error,
+			)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown list format %q, expected one of: table, json", format)
+	}
+}
+
+func showSnapshot(w io.Writer, snap *snapshot.Snapshot, format string) error {
+	if format == "snapshot" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snap)
+	}
+
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	writer, err := output.NewWriter(f)
+	if err != nil {
+		return err
+	}
+	return writer.Write(w, snap.Top)
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	RankByComments RankBy = "comments"
+	RankByArticles RankBy = "articles"
+)
+
+type (
+	// Aggregate groups articles by a derived key(domain, author etc.)
+	// and accumulates totals per group.
+	// Unlike Storage we can't drop anything during insertion because
+	// any group may reach the top with the next article, so it's a plain map.
+	Aggregate struct {
+		logger *zap.Logger
+		key    KeyFunc
+		mu     sync.Mutex
+		data   map[string]*Group
+	}
+	// KeyFunc returns the group key of the article,
+	// articles with an empty key are skipped.
+	KeyFunc func(a Article) string
+	Group   struct {
+		Key         string
+		NumComments uint64
+		NumArticles uint64
+	}
+	RankBy string
+)
+
+func NewAggregate(logger *zap.Logger, key KeyFunc) *Aggregate {
+	return &Aggregate{
+		logger: logger,
+		key:    key,
+		data:   make(map[string]*Group),
+	}
+}
+
+func (ag *Aggregate) Insert(a Article) {
+	k := ag.key(a)
+	if k == "" {
+		return
+	}
+
+	ag.mu.Lock()
+	defer ag.mu.Unlock()
+
+	g, ok := ag.data[k]
+	if !ok {
+		g = &Group{Key: k}
+		ag.data[k] = g
+	}
+	g.NumComments += a.NumComments
+	g.NumArticles++
+}
+
+// Replace moves the totals of the old article to a, keys of both may differ.
+func (ag *Aggregate) Replace(old, a Article) {
+	if k := ag.key(old); k != "" {
+		ag.mu.Lock()
+		if g, ok := ag.data[k]; ok {
+			g.NumComments -= old.NumComments
+			g.NumArticles--
+			if g.NumArticles == 0 {
+				delete(ag.data, k)
+			}
+		}
+		ag.mu.Unlock()
+	}
+
+	ag.Insert(a)
+}
+
+// Top returns up to limit groups sorted by the rank metric,
+// ties are resolved by the other metric and then by key to keep the output stable.
+func (ag *Aggregate) Top(limit int, by RankBy) []Group {
+	ag.mu.Lock()
+	groups := make([]Group, 0, len(ag.data))
+	for _, g := range ag.data {
+		groups = append(groups, *g)
+	}
+	ag.mu.Unlock()
+
+	primary, secondary := func(g Group) uint64 { return g.NumComments }, func(g Group) uint64 { return g.NumArticles }
+	if by == RankByArticles {
+		primary, secondary = secondary, primary
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if p1, p2 := primary(groups[i]), primary(groups[j]); p1 != p2 {
+			return p1 > p2
+		}
+		if s1, s2 := secondary(groups[i]), secondary(groups[j]); s1 != s2 {
+			return s1 > s2
+		}
+		return groups[i].Key < groups[j].Key
+	})
+
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+
+	return groups
+}
+
+func ParseRankBy(s string) (RankBy, bool) {
+	switch RankBy(s) {
+	case RankByComments, RankByArticles:
+		return RankBy(s), true
+	}
+	return "", false
+}
+
+// MarshalState returns the groups for checkpoints.
+func (ag *Aggregate) MarshalState() ([]byte, error) {
+	ag.mu.Lock()
+	defer ag.mu.Unlock()
+
+	groups := make([]Group, 0, len(ag.data))
+	for _, g := range ag.data {
+		groups = append(groups, *g)
+	}
+	return json.Marshal(groups)
+}
+
+// RestoreState adds the groups saved by MarshalState to the totals.
+func (ag *Aggregate) RestoreState(data []byte) error {
+	var groups []Group
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return err
+	}
+
+	ag.mu.Lock()
+	defer ag.mu.Unlock()
+
+	for _, g := range groups {
+		cur, ok := ag.data[g.Key]
+		if !ok {
+			cur = &Group{Key: g.Key}
+			ag.data[g.Key] = cur
+		}
+		cur.NumComments += g.NumComments
+		cur.NumArticles += g.NumArticles
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAggregate_Top(t *testing.T) {
+	articles := []Article{
+		{Name: "a", NumComments: 10, URL: "a.com"},
+		{Name: "b", NumComments: 1, URL: "b.com"},
+		{Name: "c", NumComments: 2, URL: "b.com"},
+		{Name: "d", NumComments: 3, URL: "b.com"},
+		{Name: "e", NumComments: 6, URL: "c.com"},
+		{Name: "f", NumComments: 100, URL: ""},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		by    RankBy
+		want  []Group
+	}{
+		{
+			name:  "by comments",
+			limit: 10,
+			by:    RankByComments,
+			want: []Group{
+				{Key: "a.com", NumComments: 10, NumArticles: 1},
+				{Key: "b.com", NumComments: 6, NumArticles: 3},
+				{Key: "c.com", NumComments: 6, NumArticles: 1},
+			},
+		},
+		{
+			name:  "by articles",
+			limit: 10,
+			by:    RankByArticles,
+			want: []Group{
+				{Key: "b.com", NumComments: 6, NumArticles: 3},
+				{Key: "a.com", NumComments: 10, NumArticles: 1},
+				{Key: "c.com", NumComments: 6, NumArticles: 1},
+			},
+		},
+		{
+			name:  "limit",
+			limit: 1,
+			by:    RankByComments,
+			want: []Group{
+				{Key: "a.com", NumComments: 10, NumArticles: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ag := NewAggregate(zap.NewNop(), func(a Article) string { return a.URL })
+			for _, a := range articles {
+				ag.Insert(a)
+			}
+
+			assert.Equal(t, tt.want, ag.Top(tt.limit, tt.by))
+		})
+	}
+}
+
+func TestParseRankBy(t *testing.T) {
+	by, ok := ParseRankBy("articles")
+	assert.True(t, ok)
+	assert.Equal(t, RankByArticles, by)
+
+	_, ok = ParseRankBy("unknown")
+	assert.False(t, ok)
+}
+
+func TestAggregate_Replace(t *testing.T) {
+	ag := NewAggregate(zap.NewNop(), func(a Article) string { return a.URL })
+	ag.Insert(Article{Name: "a", NumComments: 1, URL: "a.com"})
+	ag.Insert(Article{Name: "b", NumComments: 2, URL: "b.com"})
+
+	ag.Replace(Article{Name: "a", NumComments: 1, URL: "a.com"}, Article{Name: "a", NumComments: 4, URL: "b.com"})
+
+	assert.Equal(t, []Group{{Key: "b.com", NumComments: 6, NumArticles: 2}}, ag.Top(10, RankByComments))
+}
@@ -2,10 +2,15 @@ package storage
 
 import (
 	"container/heap"
+	"encoding/json"
+	"slices"
 	"sort"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
+
+	"articles-service/internal/metrics"
 )
 
 type (
@@ -18,12 +23,16 @@ type (
 		limit  int
 		// one reader at the end
 		// therefore no sense of sync.RWMutex
-		mu   sync.Mutex
-		data MinHeap
+		mu      sync.Mutex
+		data    MinHeap
+		metrics *metrics.Metrics
 	}
 	Article struct {
 		Name        string
 		NumComments uint64
+		Author      string
+		URL         string
+		CreatedAt   time.Time
 	}
 )
 
@@ -32,18 +41,24 @@ func New(logger *zap.Logger, limit int) *Storage {
 	heap.Init(&h)
 
 	return &Storage{
-		logger: logger,
+		logger: logger.Named("storage"),
 		limit:  limit,
 		data:   h,
 	}
 }
 
+// SetMetrics sets the heap metrics, must be called before Insert.
+func (s *Storage) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
 func (s *Storage) Insert(a Article) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if len(s.data) < s.limit {
 		heap.Push(&s.data, a)
+		s.metrics.HeapSize(len(s.data))
 		return
 	}
 
@@ -54,20 +69,101 @@ func (s *Storage) Insert(a Article) {
 
 	s.data[0] = a
 	heap.Fix(&s.data, 0)
+	s.metrics.HeapEviction()
 }
 
-func (s *Storage) TopArticlesNames() []string {
-	sort.Slice(s.data, func(i, j int) bool {
-		return s.data[i].NumComments > s.data[j].NumComments
+// Replace swaps the stored old article for a, the same article fetched again with more comments.
+// a is inserted when old isn't stored, e.g. evicted by the bigger ones.
+func (s *Storage) Replace(old, a Article) {
+	s.mu.Lock()
+	i := slices.IndexFunc(s.data, func(e Article) bool {
+		return e.Name == old.Name && e.Author == old.Author && e.CreatedAt.Equal(old.CreatedAt)
 	})
+	if i >= 0 {
+		s.data[i] = a
+		heap.Fix(&s.data, i)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.Insert(a)
+}
 
-	names := make([]string, len(s.data))
-	for i, a := range s.data {
+func (s *Storage) TopArticlesNames() []string {
+	top := s.TopArticles()
+
+	names := make([]string, len(top))
+	for i, a := range top {
 		names[i] = a.Name
 	}
 
+	return names
+}
+
+// SetLimit changes the size of the top starting from the next Reset.
+func (s *Storage) SetLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+}
+
+// Reset drops all stored articles.
+func (s *Storage) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data = NewMinHeap(s.limit)
+	s.metrics.HeapSize(0)
+}
+
+// TopArticles returns stored articles sorted by comments and resets the storage.
+func (s *Storage) TopArticles() []Article {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	top := s.data
+	sortByComments(top)
+
 	// "Be kind, help GC"
 	s.data = nil
 
-	return names
+	return top
+}
+
+// Snapshot returns the sorted copy of stored articles without resetting the storage.
+func (s *Storage) Snapshot() []Article {
+	s.mu.Lock()
+	top := slices.Clone(s.data)
+	s.mu.Unlock()
+
+	sortByComments(top)
+	return top
+}
+
+func sortByComments(top []Article) {
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].NumComments > top[j].NumComments
+	})
+}
+
+// MarshalState returns the stored articles for checkpoints.
+func (s *Storage) MarshalState() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.Marshal([]Article(s.data))
+}
+
+// RestoreState inserts articles saved by MarshalState.
+func (s *Storage) RestoreState(data []byte) error {
+	var articles []Article
+	if err := json.Unmarshal(data, &articles); err != nil {
+		return err
+	}
+	for _, a := range articles {
+		s.Insert(a)
+	}
+	return nil
 }
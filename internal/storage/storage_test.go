@@ -110,6 +110,22 @@ func TestStorage_TopArticlesNames_ClearsData(t *testing.T) {
 	assert.Equal(t, 0, len(s.data))
 }
 
+func TestStorage_Snapshot_KeepsData(t *testing.T) {
+	logger := zap.NewNop()
+	s := New(logger, 3)
+
+	s.Insert(Article{Name: "a", NumComments: 1})
+	s.Insert(Article{Name: "b", NumComments: 2})
+
+	snapshot := s.Snapshot()
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, "b", snapshot[0].Name)
+
+	// the storage keeps collecting
+	s.Insert(Article{Name: "c", NumComments: 3})
+	assert.Equal(t, []string{"c", "b", "a"}, s.TopArticlesNames())
+}
+
 func TestStorage_ZeroLimit_PanicsOnInsert(t *testing.T) {
 	logger := zap.NewNop()
 	s := New(logger, 0)
@@ -118,3 +134,16 @@ func TestStorage_ZeroLimit_PanicsOnInsert(t *testing.T) {
 		s.Insert(Article{Name: "a", NumComments: 5})
 	})
 }
+
+func TestStorage_Replace(t *testing.T) {
+	s := New(zap.NewNop(), 2)
+	s.Insert(Article{Name: "a", NumComments: 1})
+	s.Insert(Article{Name: "b", NumComments: 5})
+	s.Insert(Article{Name: "c", NumComments: 3})
+
+	// stored one is swapped, the evicted one is inserted
+	s.Replace(Article{Name: "c", NumComments: 3}, Article{Name: "c", NumComments: 7})
+	s.Replace(Article{Name: "a", NumComments: 1}, Article{Name: "a", NumComments: 6})
+
+	assert.Equal(t, []string{"c", "a"}, s.TopArticlesNames())
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal/window"
+)
+
+type (
+	// Windowed keeps a separate top storage per time window of the article created_at.
+	Windowed struct {
+		logger *zap.Logger
+		limit  int
+		spec   window.Spec
+		mu     sync.Mutex
+		data   map[time.Time]*Storage
+	}
+	WindowTop struct {
+		Window   window.Range
+		Articles []Article
+	}
+)
+
+func NewWindowed(logger *zap.Logger, limit int, spec window.Spec) *Windowed {
+	return &Windowed{
+		logger: logger,
+		limit:  limit,
+		spec:   spec,
+		data:   make(map[time.Time]*Storage),
+	}
+}
+
+// Insert skips articles without created_at.
+func (w *Windowed) Insert(a Article) {
+	if a.CreatedAt.IsZero() {
+		return
+	}
+
+	for _, win := range w.spec.Windows(a.CreatedAt) {
+		w.window(win.Since).Insert(a)
+	}
+}
+
+// Replace swaps the article in its windows, both have the same created_at.
+func (w *Windowed) Replace(old, a Article) {
+	if a.CreatedAt.IsZero() {
+		return
+	}
+
+	for _, win := range w.spec.Windows(a.CreatedAt) {
+		w.window(win.Since).Replace(old, a)
+	}
+}
+
+func (w *Windowed) window(start time.Time) *Storage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s, ok := w.data[start]
+	if !ok {
+		s = New(w.logger, w.limit)
+		w.data[start] = s
+	}
+	return s
+}
+
+// Top returns top articles per window ordered by window start and resets the storage.
+func (w *Windowed) Top() []WindowTop {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	res := make([]WindowTop, 0, len(w.data))
+	for start, s := range w.data {
+		res = append(res, WindowTop{
+			Window:   window.Range{Since: start, Until: start.Add(w.spec.Size)},
+			Articles: s.TopArticles(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Window.Since.Before(res[j].Window.Since)
+	})
+
+	w.data = make(map[time.Time]*Storage)
+
+	return res
+}
+
+type windowState struct {
+	Since    time.Time
+	Articles json.RawMessage
+}
+
+// MarshalState returns the articles per window for checkpoints.
+func (w *Windowed) MarshalState() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	states := make([]windowState, 0, len(w.data))
+	for start, s := range w.data {
+		data, err := s.MarshalState()
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, windowState{Since: start, Articles: data})
+	}
+	return json.Marshal(states)
+}
+
+// RestoreState inserts the articles per window saved by MarshalState.
+func (w *Windowed) RestoreState(data []byte) error {
+	var states []windowState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return err
+	}
+	for _, st := range states {
+		if err := w.window(st.Since).RestoreState(st.Articles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
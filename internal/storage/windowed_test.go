@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"articles-service/internal/window"
+)
+
+func TestWindowed_Top(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	midnight := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	spec, err := window.NewSpec(window.Tumbling, 24*time.Hour, 0)
+	require.NoError(t, err)
+
+	w := NewWindowed(zap.NewNop(), 2, spec)
+	w.Insert(Article{Name: "a", NumComments: 1, CreatedAt: day(2)})
+	w.Insert(Article{Name: "b", NumComments: 3, CreatedAt: day(2)})
+	w.Insert(Article{Name: "c", NumComments: 2, CreatedAt: day(2)})
+	w.Insert(Article{Name: "d", NumComments: 5, CreatedAt: day(1)})
+	w.Insert(Article{Name: "no created_at", NumComments: 100})
+
+	got := w.Top()
+	require.Len(t, got, 2)
+
+	assert.Equal(t, window.Range{Since: midnight(1), Until: midnight(2)}, got[0].Window)
+	assert.Equal(t, []string{"d"}, names(got[0].Articles))
+	assert.Equal(t, window.Range{Since: midnight(2), Until: midnight(3)}, got[1].Window)
+	assert.Equal(t, []string{"b", "c"}, names(got[1].Articles))
+
+	assert.Empty(t, w.Top(), "top resets the storage")
+}
+
+func names(articles []Article) []string {
+	res := make([]string, len(articles))
+	for i, a := range articles {
+		res[i] = a.Name
+	}
+	return res
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+const (
+	ServiceName = "articles-service"
+
+	// exporters
+	None   Exporter = ""
+	OTLP   Exporter = "otlp"
+	Stdout Exporter = "stdout"
+	File   Exporter = "file"
+)
+
+type (
+	Exporter string
+	Config   struct {
+		Exporter Exporter
+		// OTLP/HTTP endpoint(host:port or url), default from OTEL_EXPORTER_OTLP_* env or localhost:4318
+		Endpoint string
+		// file of the file exporter
+		File string
+	}
+)
+
+func ParseExporter(s string) (Exporter, error) {
+	switch e := Exporter(s); e {
+	case None, OTLP, Stdout, File:
+		return e, nil
+	}
+	return "", fmt.Errorf("unknown trace exporter %q, expected one of: otlp, stdout, file", s)
+}
+
+// Setup installs the global tracer provider exporting spans by the config,
+// tracing stays noop without an exporter. Shutdown flushes spans left.
+func Setup(ctx context.Context, cfg Config) (shutdown func(ctx context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+
+	var (
+		exp    sdktrace.SpanExporter
+		closer io.Closer
+	)
+	switch cfg.Exporter {
+	case None:
+		return noop, nil
+	case OTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			if strings.Contains(cfg.Endpoint, "://") {
+				opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+			} else {
+				opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint), otlptracehttp.WithInsecure())
+			}
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	case Stdout:
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case File:
+		if cfg.File == "" {
+			return noop, errors.New("file exporter needs the file")
+		}
+		f, ferr := os.Create(cfg.File)
+		if ferr != nil {
+			return noop, ferr
+		}
+		closer = f
+		// a span per line
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return noop, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return noop, fmt.Errorf("create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return noop, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestParseExporter(t *testing.T) {
+	for _, s := range []string{"", "otlp", "stdout", "file"} {
+		e, err := ParseExporter(s)
+		require.NoError(t, err)
+		assert.Equal(t, Exporter(s), e)
+	}
+
+	_, err := ParseExporter("jaeger")
+	assert.Error(t, err)
+}
+
+func TestSetup_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: File, File: path})
+	require.NoError(t, err)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "run")
+	_, child := otel.Tracer("test").Start(ctx, "page")
+	child.End()
+	parent.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	type span struct {
+		Name        string
+		SpanContext struct{ TraceID string }
+		Parent      struct{ SpanID string }
+	}
+	var spans [2]span
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &spans[i]))
+	}
+	// children end first
+	assert.Equal(t, "page", spans[0].Name)
+	assert.Equal(t, "run", spans[1].Name)
+	assert.Equal(t, spans[1].SpanContext.TraceID, spans[0].SpanContext.TraceID)
+	assert.NotEmpty(t, spans[0].Parent.SpanID)
+}
+
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
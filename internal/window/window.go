@@ -0,0 +1,119 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Tumbling Kind = "tumbling"
+	Sliding  Kind = "sliding"
+
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+type (
+	// Range is the [Since, Until) interval, zero bound is open.
+	Range struct {
+		Since time.Time
+		Until time.Time
+	}
+	// Spec describes a series of windows: tumbling windows follow each other
+	// without gaps and overlaps(Step == Size), sliding windows overlap and start every Step.
+	Spec struct {
+		Kind Kind
+		Size time.Duration
+		Step time.Duration
+	}
+	Kind string
+)
+
+// Last returns the range of the last d relative to the reference time.
+func Last(ref time.Time, d time.Duration) Range {
+	return Range{Since: ref.Add(-d), Until: ref}
+}
+
+func (r Range) IsZero() bool { return r.Since.IsZero() && r.Until.IsZero() }
+
+func (r Range) Contains(t time.Time) bool {
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && !t.Before(r.Until) {
+		return false
+	}
+	return true
+}
+
+func (r Range) String() string {
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	return format(r.Since) + " .. " + format(r.Until)
+}
+
+func NewSpec(kind Kind, size, step time.Duration) (Spec, error) {
+	if size <= 0 {
+		return Spec{}, errors.New("window size must be positive")
+	}
+
+	switch kind {
+	case Tumbling:
+		step = size
+	case Sliding:
+		if step <= 0 || step > size {
+			return Spec{}, fmt.Errorf("sliding window step must be in (0, %s]", size)
+		}
+	default:
+		return Spec{}, fmt.Errorf("unknown window kind %q, expected %s|%s", kind, Tumbling, Sliding)
+	}
+
+	return Spec{Kind: kind, Size: size, Step: step}, nil
+}
+
+// Windows returns all windows containing t ordered by start,
+// windows are aligned to the zero time(UTC midnight for whole days)
+// so they are the same for every run.
+func (s Spec) Windows(t time.Time) []Range {
+	if s.Step <= 0 || s.Size <= 0 {
+		return nil
+	}
+
+	// walk back from the latest window start while windows still contain t
+	var res []Range
+	for start := t.Truncate(s.Step); start.Add(s.Size).After(t); start = start.Add(-s.Step) {
+		res = append(res, Range{Since: start, Until: start.Add(s.Size)})
+	}
+	slices.Reverse(res)
+
+	return res
+}
+
+// ParseDuration extends time.ParseDuration with days(d) and weeks(w) units,
+// e.g. "7d", "1w", "36h". Mixed units with days("1d12h") are not supported.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": week} {
+		if num, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,118 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hour(h int) time.Time { return time.Date(2024, 1, 1, h, 0, 0, 0, time.UTC) }
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "36h", want: 36 * time.Hour},
+		{in: "7d", want: 7 * 24 * time.Hour},
+		{in: "1.5d", want: 36 * time.Hour},
+		{in: "1w", want: 7 * 24 * time.Hour},
+		{in: "90m", want: 90 * time.Minute},
+		{in: "-1d", wantErr: true},
+		{in: "xd", wantErr: true},
+		{in: "week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDuration(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRange_Contains(t *testing.T) {
+	r := Last(hour(10), 2*time.Hour)
+
+	assert.False(t, r.Contains(hour(7)))
+	assert.True(t, r.Contains(hour(8)))
+	assert.True(t, r.Contains(hour(9)))
+	assert.False(t, r.Contains(hour(10)), "until is exclusive")
+
+	assert.True(t, Range{}.Contains(hour(1)), "zero range is open")
+	assert.True(t, Range{}.IsZero())
+	assert.True(t, Range{Since: hour(1)}.Contains(hour(23)))
+}
+
+func TestNewSpec(t *testing.T) {
+	spec, err := NewSpec(Tumbling, time.Hour, 10*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, spec.Step, "tumbling step equals size")
+
+	_, err = NewSpec(Sliding, time.Hour, 0)
+	require.Error(t, err)
+	_, err = NewSpec(Sliding, time.Hour, 2*time.Hour)
+	require.Error(t, err)
+	_, err = NewSpec("hopping", time.Hour, time.Hour)
+	require.Error(t, err)
+	_, err = NewSpec(Tumbling, 0, 0)
+	require.Error(t, err)
+}
+
+func TestSpec_Windows(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+		at   time.Time
+		want []Range
+	}{
+		{
+			name: "tumbling",
+			spec: Spec{Kind: Tumbling, Size: 6 * time.Hour, Step: 6 * time.Hour},
+			at:   hour(7).Add(time.Minute),
+			want: []Range{{Since: hour(6), Until: hour(12)}},
+		},
+		{
+			name: "tumbling on the boundary",
+			spec: Spec{Kind: Tumbling, Size: 6 * time.Hour, Step: 6 * time.Hour},
+			at:   hour(12),
+			want: []Range{{Since: hour(12), Until: hour(18)}},
+		},
+		{
+			name: "sliding",
+			spec: Spec{Kind: Sliding, Size: 3 * time.Hour, Step: time.Hour},
+			at:   hour(5).Add(time.Minute),
+			want: []Range{
+				{Since: hour(3), Until: hour(6)},
+				{Since: hour(4), Until: hour(7)},
+				{Since: hour(5), Until: hour(8)},
+			},
+		},
+		{
+			name: "sliding with step not dividing size",
+			spec: Spec{Kind: Sliding, Size: 5 * time.Hour, Step: 2 * time.Hour},
+			at:   hour(6),
+			want: []Range{
+				{Since: hour(2), Until: hour(7)},
+				{Since: hour(4), Until: hour(9)},
+				{Since: hour(6), Until: hour(11)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.spec.Windows(tt.at))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package articles is the in-process API of the articles service:
+// the top of the most commented articles without running the binary.
+//
+//	svc, err := articles.New(articles.WithLimit(10), articles.WithLogger(logger))
+//	if err != nil {
+//		return err
+//	}
+//	top, err := svc.TopArticles(ctx)
+package articles
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"articles-service/internal"
+	"articles-service/internal/output"
+	"articles-service/internal/storage"
+)
+
+// MaxLimit is the max number of articles in the top.
+const MaxLimit = internal.MaxLimit
+
+// output formats of WithOutput
+const (
+	Text     = output.Text
+	JSON     = output.JSON
+	NDJSON   = output.NDJSON
+	CSV      = output.CSV
+	TSV      = output.TSV
+	Markdown = output.Markdown
+	Table    = output.Table
+)
+
+type (
+	// Article of the top, sorted by comments.
+	Article = storage.Article
+	// Record is the ranked row written to the output.
+	Record = output.Record
+	Format = output.Format
+
+	// Service crawls the articles api, safe for concurrent use: crawls run one at a time.
+	Service struct {
+		logger *zap.Logger
+		opts   internal.Options
+		// nil if the top is not written
+		out io.Writer
+
+		mu  sync.Mutex
+		app *internal.App
+	}
+	Option func(s *Service)
+)
+
+// WithLimit sets the number of articles in the top, required: 1..MaxLimit.
+func WithLimit(n int) Option {
+	return func(s *Service) { s.opts.Limit = n }
+}
+
+// WithSource sets the articles api endpoint, e.g. a mirror.
+func WithSource(url string) Option {
+	return func(s *Service) { s.opts.Source = url }
+}
+
+// WithStorage persists every run into the snapshot store directory,
+// the same store the binary reads with -snapshot-dir.
+func WithStorage(dir string) Option {
+	return func(s *Service) { s.opts.SnapshotDir = dir }
+}
+
+// WithLogger sets the logger, nothing is logged by default.
+func WithLogger(logger *zap.Logger) Option {
+	return func(s *Service) { s.logger = logger }
+}
+
+// WithOutput writes the top of every run to w in the format.
+func WithOutput(w io.Writer, f Format) Option {
+	return func(s *Service) { s.out, s.opts.Format = w, f }
+}
+
+// New builds the service, invalid options are returned as errors.
+func New(opts ...Option) (*Service, error) {
+	s := &Service{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	var err error
+	if s.app, err = internal.NewEmbedded(s.logger, s.opts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// TopArticles runs the crawl and returns the top, the run is persisted
+// and written to the output when enabled.
+func (s *Service) TopArticles(ctx context.Context) ([]Article, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, err := s.app.Crawl(ctx, s.out)
+	if err != nil {
+		return nil, err
+	}
+
+	top := make([]Article, len(run.Top))
+	for i, r := range run.Top {
+		top[i] = Article{Name: r.Title, NumComments: r.Comments, Author: r.Author, URL: r.URL, CreatedAt: r.CreatedAt}
+	}
+	return top, nil
+}
@@ -0,0 +1,108 @@
+package articles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"articles-service/internal/articlesapi"
+	"articles-service/internal/snapshot"
+)
+
+// upstream serves rows by pages of 2 like the articles api, comments of the row i are rows[i].
+func upstream(rows ...int) http.Handler {
+	const perPage = 2
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		resp := articlesapi.Response{
+			Page:       page,
+			PerPage:    perPage,
+			Total:      len(rows),
+			TotalPages: (len(rows) + perPage - 1) / perPage,
+		}
+		for i := (page - 1) * perPage; i < min(page*perPage, len(rows)); i++ {
+			title, comments := "row "+strconv.Itoa(i), rows[i]
+			resp.Data = append(resp.Data, &articlesapi.Article{Title: &title, NumComments: &comments})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "no limit", wantErr: "please provide limit"},
+		{name: "limit above max", opts: []Option{WithLimit(MaxLimit + 1)}, wantErr: "limit is out of range"},
+		{name: "nil logger", opts: []Option{WithLimit(1), WithLogger(nil)}, wantErr: "logger is nil"},
+		{name: "unknown format", opts: []Option{WithLimit(1), WithOutput(&bytes.Buffer{}, "yaml")}, wantErr: "unknown format"},
+		{name: "valid", opts: []Option{WithLimit(10), WithSource("http://localhost"), WithOutput(&bytes.Buffer{}, JSON)}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := New(tt.opts...)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, svc)
+		})
+	}
+}
+
+func TestService_TopArticles(t *testing.T) {
+	srv := httptest.NewServer(upstream(5, 1, 9, 3, 7))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	dir := t.TempDir()
+	svc, err := New(WithLimit(3), WithSource(srv.URL), WithOutput(&out, NDJSON), WithStorage(dir))
+	require.NoError(t, err)
+
+	top, err := svc.TopArticles(context.Background())
+	require.NoError(t, err)
+	require.Len(t, top, 3)
+	assert.Equal(t, []string{"row 2", "row 4", "row 0"}, []string{top[0].Name, top[1].Name, top[2].Name})
+	assert.Equal(t, 3, bytes.Count(out.Bytes(), []byte("\n")))
+
+	store, err := snapshot.Open(dir)
+	require.NoError(t, err)
+	snap, err := store.Get(snapshot.Latest)
+	require.NoError(t, err)
+	require.Len(t, snap.Top, 3)
+	assert.Equal(t, uint64(9), snap.Top[0].Comments)
+	assert.Equal(t, 3, snap.Report.Pages.Fetched)
+
+	// the service is reusable
+	_, err = svc.TopArticles(context.Background())
+	require.NoError(t, err)
+	store, err = snapshot.Open(dir)
+	require.NoError(t, err)
+	assert.Len(t, store.List(), 2)
+}
+
+func TestService_TopArticles_Canceled(t *testing.T) {
+	srv := httptest.NewServer(upstream(1, 2, 3))
+	defer srv.Close()
+
+	svc, err := New(WithLimit(3), WithSource(srv.URL))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = svc.TopArticles(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}